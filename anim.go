@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"time"
+)
+
+// animation is a decoded image as a sequence of fully composited frames.
+// Still images have a single frame.
+type animation struct {
+	frames    []image.Image // nil for the TIFF pages decodeOptions.page left out
+	delays    []int         // per frame, in 100ths of a second
+	loopCount int           // as in gif.GIF: 0 loops forever, -1 plays once
+	format    string        // the decoder that read it, such as "png" or "svg"
+}
+
+// decodeOptions controls decodeAnimation.
+type decodeOptions struct {
+	// autorotate turns a still image or TIFF page upright according to its
+	// EXIF orientation.
+	autorotate bool
+	// svgSize picks the raster size of an SVG from its intrinsic width and
+	// height.
+	svgSize func(w, h float64) (int, int, error)
+	// page is the one page of a multi-page TIFF to decode, or -1 to decode
+	// every page. A GIF is always decoded whole, as each frame is drawn
+	// over the ones before it.
+	page int
+	// maxPixels, if > 0, bounds the size of each TIFF page decoded. It is
+	// checked before the page's pixels are allocated.
+	maxPixels int
+}
+
+// pixelLimitError is an image over a limit on its number of pixels.
+type pixelLimitError struct {
+	width, height, limit int
+}
+
+func (e *pixelLimitError) Error() string {
+	return fmt.Sprintf("image of %dx%d pixels is over the limit of %d", e.width, e.height, e.limit)
+}
+
+// decodeAnimation decodes r, keeping every frame of a GIF, the pages of a
+// TIFF opts asks for, and the single frame of any other format.
+// Data that can't be decoded fails with a *decodeError, and a TIFF page over
+// opts.maxPixels or a WebP canvas over maxWebPCanvas with a *pixelLimitError;
+// errors reading r are returned unwrapped.
+func decodeAnimation(r io.Reader, opts decodeOptions) (animation, error) {
+	rr := &readRecorder{r: r}
+	br := bufio.NewReader(rr)
+	head, _ := br.Peek(1024)
+	anim, err := decodeFrames(br, opts)
+	var pe *pixelLimitError
+	switch {
+	case err == nil:
+		return anim, nil
+	case rr.err != nil:
+		return animation{}, rr.err
+	case errors.As(err, &pe):
+		return animation{}, err
+	default:
+		return animation{}, &decodeError{format: sniffFormat(head), empty: len(head) == 0, err: err}
+	}
+}
+
+// decodeFrames does the work of decodeAnimation.
+func decodeFrames(br *bufio.Reader, opts decodeOptions) (animation, error) {
+	if head, _ := br.Peek(1024); isSVG(head) {
+		img, err := decodeSVG(br, opts.svgSize)
+		if err != nil {
+			return animation{}, err
+		}
+		return animation{frames: []image.Image{img}, delays: []int{0}, loopCount: -1, format: "svg"}, nil
+	}
+	if magic, _ := br.Peek(6); string(magic) == "GIF87a" || string(magic) == "GIF89a" {
+		g, err := gif.DecodeAll(br)
+		if err != nil {
+			return animation{}, err
+		}
+		var frames []image.Image
+		if len(g.Image) == 1 && g.Image[0].Bounds() == image.Rect(0, 0, g.Config.Width, g.Config.Height) {
+			// A still GIF filling its canvas needs no compositing; keeping
+			// it paletted lets the renderer see its transparent index.
+			frames = []image.Image{g.Image[0]}
+		} else {
+			frames = compositeGIF(g)
+		}
+		return animation{frames: frames, delays: g.Delay, loopCount: g.LoopCount, format: "gif"}, nil
+	}
+	if magic, _ := br.Peek(12); len(magic) == 12 && string(magic[8:]) == "WEBP" {
+		// Only the first frame of an animated WebP is decoded for now.
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return animation{}, err
+		}
+		still, at, canvas, err := webpStill(data)
+		if err != nil {
+			return animation{}, err
+		}
+		if still != nil {
+			data = still
+		}
+		img, format, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return animation{}, err
+		}
+		if still != nil {
+			img = placeFrame(img, at, canvas)
+		}
+		return animation{frames: []image.Image{img}, delays: []int{0}, loopCount: -1, format: format}, nil
+	}
+	if magic, _ := br.Peek(8); tiffByteOrder(magic) != nil {
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return animation{}, err
+		}
+		if pages := tiffPages(data); len(pages) > 0 {
+			// Each page of a multi-page TIFF, such as a scanned document,
+			// is a frame, though only the ones asked for are decoded.
+			frames := make([]image.Image, len(pages))
+			for i, off := range pages {
+				if opts.page >= 0 && i != opts.page {
+					continue
+				}
+				img, err := decodeTIFFPage(data, off, opts.maxPixels)
+				if err != nil {
+					if len(pages) > 1 {
+						err = fmt.Errorf("page %d: %w", i, err)
+					}
+					return animation{}, err
+				}
+				if opts.autorotate {
+					img = applyOrientation(img, exifOrientation(data))
+				}
+				frames[i] = img
+			}
+			return animation{frames: frames, delays: make([]int, len(frames)), loopCount: -1, format: "tiff"}, nil
+		}
+		br = bufio.NewReader(bytes.NewReader(data))
+	}
+	if !opts.autorotate {
+		img, format, err := image.Decode(br)
+		if err != nil {
+			return animation{}, err
+		}
+		return animation{frames: []image.Image{img}, delays: []int{0}, loopCount: -1, format: format}, nil
+	}
+	// The EXIF block can sit anywhere in the header, so buffer the file.
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return animation{}, err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return animation{}, err
+	}
+	img = applyOrientation(img, exifOrientation(data))
+	return animation{frames: []image.Image{img}, delays: []int{0}, loopCount: -1, format: format}, nil
+}
+
+// compositeGIF draws each frame of g over the frames before it, honoring
+// each frame's disposal method, and returns a full-canvas snapshot per frame.
+func compositeGIF(g *gif.GIF) []image.Image {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		for _, fr := range g.Image {
+			bounds = bounds.Union(fr.Bounds())
+		}
+	}
+	canvas := image.NewRGBA(bounds)
+	out := make([]image.Image, len(g.Image))
+	for i, fr := range g.Image {
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var saved *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			saved = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, fr.Bounds(), fr, fr.Bounds().Min, draw.Over)
+		out[i] = cloneRGBA(canvas)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, fr.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = saved
+		}
+	}
+	return out
+}
+
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
+
+// minFrameDelay is used for frames whose delay is shorter, matching how
+// browsers treat GIFs that ask for 0 or 1 hundredths of a second.
+const minFrameDelay = 100 * time.Millisecond
+
+// playAnimation draws each frame's rows in place, waiting out the frame's
+// delay before the next, and repeats per loopCount. It hides the cursor while
+// playing and restores it on return, including when stop fires.
+func playAnimation(out *bufio.Writer, frames [][]string, delays []int, loopCount int, stop <-chan os.Signal) {
+	out.WriteString("\x1b[?25l")
+	defer func() {
+		out.WriteString(restoreTerminal)
+		out.Flush()
+	}()
+
+	for pass := 0; loopCount == 0 || pass <= max(loopCount, 0); pass++ {
+		for i, rows := range frames {
+			out.WriteString("\x1b[H\x1b[2J")
+			writeRows(out, rows)
+			out.Flush()
+
+			delay := minFrameDelay
+			if i < len(delays) && delays[i] > 1 {
+				delay = time.Duration(delays[i]) * 10 * time.Millisecond
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(delay):
+			}
+		}
+	}
+}
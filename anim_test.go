@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+// failingReader returns some bytes and then err.
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestDecodeAnimationErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+	full := buf.Bytes()
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, "empty file"},
+		{"truncated png", full[:len(full)/2], "truncated png data"},
+		{"png header only", full[:8], "truncated png data"},
+		{"not an image", []byte("hello, world\n"), "not an image in any supported format"},
+	} {
+		_, err := decodeAnimation(bytes.NewReader(tc.data), decodeOptions{autorotate: true})
+		var de *decodeError
+		if !errors.As(err, &de) {
+			t.Errorf("%s: got %v, want a *decodeError", tc.name, err)
+			continue
+		}
+		if err.Error() != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	// A failure to read is passed through, not taken for a bad image.
+	ioErr := errors.New("device not ready")
+	_, err := decodeAnimation(&failingReader{data: full[:len(full)/2], err: ioErr}, decodeOptions{autorotate: true})
+	if !errors.Is(err, ioErr) {
+		t.Errorf("read error: got %v, want %v", err, ioErr)
+	}
+	var de *decodeError
+	if errors.As(err, &de) {
+		t.Errorf("read error: got a *decodeError %q", err)
+	}
+	if _, err := decodeAnimation(bytes.NewReader(full), decodeOptions{autorotate: true}); err != nil {
+		t.Errorf("whole png: %v", err)
+	}
+}
+
+// solidVP8L returns a lossless WebP bitstream of a w x h image all of color
+// c. Every prefix code holds a single symbol, so the pixels take no bits.
+func solidVP8L(w, h int, c color.NRGBA) []byte {
+	var out []byte
+	var acc uint64
+	var n uint
+	put := func(v uint64, bits uint) {
+		acc |= v << n
+		for n += bits; n >= 8; n -= 8 {
+			out = append(out, byte(acc))
+			acc >>= 8
+		}
+	}
+	put(0x2f, 8) // signature
+	put(uint64(w-1), 14)
+	put(uint64(h-1), 14)
+	put(1, 1) // alpha is used
+	put(0, 3) // version
+	put(0, 1) // no transform
+	put(0, 1) // no color cache
+	put(0, 1) // no meta prefix codes
+	// Green, red, blue, alpha, and distance codes.
+	for _, sym := range []uint8{c.G, c.R, c.B, c.A, 0} {
+		put(1, 1) // simple code
+		put(0, 1) // one symbol
+		put(1, 1) // of 8 bits
+		put(uint64(sym), 8)
+	}
+	if n > 0 {
+		out = append(out, byte(acc))
+	}
+	return out
+}
+
+// riffChunk returns a chunk with its header and padding.
+func riffChunk(fourCC string, payload []byte) []byte {
+	b := append([]byte(fourCC), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(payload)))
+	b = append(b, payload...)
+	if len(payload)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func TestDecodeAnimatedWebP(t *testing.T) {
+	// A 6x4 canvas whose first frame is 2x2 and red at (2, 2), and whose
+	// second fills it with blue.
+	red, blue := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}
+	vp8x := func(w, h int) []byte {
+		b := append([]byte{0x12, 0, 0, 0}, putUint24(uint32(w-1))...) // animation and alpha
+		return riffChunk("VP8X", append(b, putUint24(uint32(h-1))...))
+	}
+	frame := func(x, y, w, h int, c color.NRGBA) []byte {
+		hdr := append(putUint24(uint32(x/2)), putUint24(uint32(y/2))...)
+		hdr = append(hdr, putUint24(uint32(w-1))...)
+		hdr = append(hdr, putUint24(uint32(h-1))...)
+		hdr = append(hdr, putUint24(100)...) // duration
+		hdr = append(hdr, 0)                 // blending and disposal
+		return riffChunk("ANMF", append(hdr, riffChunk("VP8L", solidVP8L(w, h, c))...))
+	}
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = append(body, vp8x(6, 4)...)
+	body = append(body, riffChunk("ANIM", []byte{0, 0, 0, 0, 0, 0})...)
+	body = append(body, frame(2, 2, 2, 2, red)...)
+	firstEnd := 8 + len(body) // the end of the first frame in the file
+	body = append(body, frame(0, 0, 6, 4, blue)...)
+	data := riffChunk("RIFF", body)
+
+	anim, err := decodeAnimation(bytes.NewReader(data), decodeOptions{autorotate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := anim.frames[0]
+	if b := img.Bounds(); b != image.Rect(0, 0, 6, 4) {
+		t.Fatalf("bounds %v, want the 6x4 canvas", b)
+	}
+	if anim.format != "webp" || len(anim.frames) != 1 {
+		t.Errorf("format %q with %d frames, want webp with 1", anim.format, len(anim.frames))
+	}
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{2, 2, red}, {3, 3, red}, {1, 2, color.NRGBA{}}, {4, 3, color.NRGBA{}}, {0, 0, color.NRGBA{}},
+	} {
+		if got := color.NRGBAModel.Convert(img.At(tc.x, tc.y)).(color.NRGBA); got != tc.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", tc.x, tc.y, got, tc.want)
+		}
+	}
+
+	// Cut before the end of the first frame, the only one decoded, the file
+	// is an error, never a panic.
+	for n := 0; n < firstEnd; n++ {
+		if _, err := decodeAnimation(bytes.NewReader(data[:n]), decodeOptions{autorotate: true}); err == nil {
+			t.Errorf("truncated to %d of %d bytes: no error", n, len(data))
+		}
+	}
+	// An ANMF chunk claiming more bytes than the file holds isn't rewrapped.
+	bad := bytes.Clone(data)
+	anmf := bytes.Index(bad, []byte("ANMF"))
+	binary.LittleEndian.PutUint32(bad[anmf+4:], 1<<20)
+	if still, _, _, _ := webpStill(bad); still != nil {
+		t.Error("ANMF longer than the file: rewrapped")
+	}
+	if _, err := decodeAnimation(bytes.NewReader(bad), decodeOptions{autorotate: true}); err == nil {
+		t.Error("ANMF longer than the file: no error")
+	}
+
+	// A canvas too big to allocate is refused before it is, and so is a
+	// frame that doesn't fit on its canvas.
+	for _, tc := range []struct {
+		name          string
+		canvas, frame []byte
+		limit         bool // fails with a *pixelLimitError
+	}{
+		{"huge canvas", vp8x(1<<24, 1<<24), frame(0, 0, 1, 1, red), true},
+		{"wide canvas", vp8x(1<<24, 4), frame(0, 0, 1, 1, red), true},
+		{"frame past the right edge", vp8x(6, 4), frame(4, 0, 4, 4, red), false},
+		{"frame past the bottom edge", vp8x(6, 4), frame(0, 2, 2, 4, red), false},
+		{"frame bigger than the canvas", vp8x(6, 4), frame(0, 0, 8, 8, red), false},
+	} {
+		body := append([]byte("WEBP"), tc.canvas...)
+		body = append(body, riffChunk("ANIM", []byte{0, 0, 0, 0, 0, 0})...)
+		body = append(body, tc.frame...)
+		_, err := decodeAnimation(bytes.NewReader(riffChunk("RIFF", body)), decodeOptions{})
+		var pe *pixelLimitError
+		switch {
+		case err == nil:
+			t.Errorf("%s: no error", tc.name)
+		case tc.limit != errors.As(err, &pe):
+			t.Errorf("%s: got %v, want a *pixelLimitError: %t", tc.name, err, tc.limit)
+		}
+	}
+}
+
+// tiffTestPage is a page of the file grayTIFF builds.
+type tiffTestPage struct {
+	w, h    int
+	gray    uint8
+	reduced bool // marked as a reduced-resolution copy, such as a thumbnail
+}
+
+// grayTIFF returns a little-endian TIFF with an uncompressed 8-bit gray IFD
+// per page, each linking to the next, and the offsets of those IFDs.
+func grayTIFF(pages ...tiffTestPage) ([]byte, []uint32) {
+	bo := binary.LittleEndian
+	data := []byte("II*\x00\x00\x00\x00\x00")
+	var ifds []uint32
+	next := 4 // where the offset of the next IFD goes
+	for _, p := range pages {
+		pixels := len(data)
+		data = append(data, bytes.Repeat([]byte{p.gray}, p.w*p.h)...)
+		if len(data)%2 == 1 {
+			data = append(data, 0)
+		}
+		subfile := uint32(0)
+		if p.reduced {
+			subfile = 1
+		}
+		entries := [][3]uint32{ // tag, type, value
+			{tiffSubfileTypeTag, 4, subfile},
+			{256, 4, uint32(p.w)},       // ImageWidth
+			{257, 4, uint32(p.h)},       // ImageLength
+			{258, 3, 8},                 // BitsPerSample
+			{259, 3, 1},                 // Compression: none
+			{262, 3, 1},                 // PhotometricInterpretation: black is zero
+			{273, 4, uint32(pixels)},    // StripOffsets
+			{278, 4, uint32(p.h)},       // RowsPerStrip
+			{279, 4, uint32(p.w * p.h)}, // StripByteCounts
+		}
+		off := uint32(len(data))
+		bo.PutUint32(data[next:], off)
+		ifds = append(ifds, off)
+		data = bo.AppendUint16(data, uint16(len(entries)))
+		for _, e := range entries {
+			data = bo.AppendUint16(data, uint16(e[0]))
+			data = bo.AppendUint16(data, uint16(e[1]))
+			data = bo.AppendUint32(data, 1)
+			data = bo.AppendUint32(data, e[2])
+		}
+		next = len(data)
+		data = append(data, 0, 0, 0, 0)
+	}
+	return data, ifds
+}
+
+func TestTIFFPages(t *testing.T) {
+	full, thumb := tiffTestPage{w: 4, h: 3, gray: 200}, tiffTestPage{w: 2, h: 1, gray: 50, reduced: true}
+	data, ifds := grayTIFF(full, thumb, full)
+	if got := tiffPages(data); !slices.Equal(got, []uint32{ifds[0], ifds[2]}) {
+		t.Errorf("thumbnail between pages: got %v, want %v", got, []uint32{ifds[0], ifds[2]})
+	}
+	data, ifds = grayTIFF(thumb, full)
+	if got := tiffPages(data); !slices.Equal(got, ifds[1:]) {
+		t.Errorf("thumbnail first: got %v, want %v", got, ifds[1:])
+	}
+
+	// The chain stops where it loops back, is cut off, or points outside
+	// the file.
+	data, ifds = grayTIFF(full, full)
+	last := len(data) - 4
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want []uint32
+	}{
+		{"loop", putNext(data, last, ifds[0]), ifds},
+		{"self loop", putNext(data, last, ifds[1]), ifds},
+		{"past the end", putNext(data, last, uint32(len(data))), ifds},
+		{"far past the end", putNext(data, last, 1<<31), ifds},
+		{"into the header", putNext(data, last, 4), ifds},
+		{"first past the end", putNext(data, 4, 1<<31), nil},
+		{"cut in the second IFD", data[:int(ifds[1])+10], ifds[:1]},
+		{"cut before the next offset", data[:last], ifds[:1]},
+		{"header only", data[:8], nil},
+		{"not a TIFF", []byte("GIF89a\x00\x00"), nil},
+	} {
+		if got := tiffPages(tc.data); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// setTIFFPage makes the second page the one a decoder sees.
+	data, ifds = grayTIFF(full, tiffTestPage{w: 5, h: 7})
+	setTIFFPage(data, ifds[1])
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 5 || cfg.Height != 7 {
+		t.Errorf("after setTIFFPage: %dx%d, want 5x7", cfg.Width, cfg.Height)
+	}
+}
+
+// putNext returns a copy of data with the IFD offset at i set to off.
+func putNext(data []byte, i int, off uint32) []byte {
+	data = bytes.Clone(data)
+	binary.LittleEndian.PutUint32(data[i:], off)
+	return data
+}
+
+func TestDecodeTIFFPage(t *testing.T) {
+	small, big := tiffTestPage{w: 4, h: 3, gray: 200}, tiffTestPage{w: 40, h: 30, gray: 50}
+	data, _ := grayTIFF(small, big)
+
+	// -frame 1 decodes the second page alone.
+	anim, err := decodeAnimation(bytes.NewReader(data), decodeOptions{page: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.frames) != 2 || anim.frames[0] != nil || anim.frames[1] == nil {
+		t.Fatalf("page 1: got frames %v, want the second of 2 alone", anim.frames)
+	}
+	if b := anim.frames[1].Bounds(); b != image.Rect(0, 0, 40, 30) {
+		t.Errorf("page 1: bounds %v, want 40x30", b)
+	}
+	if got := color.GrayModel.Convert(anim.frames[1].At(3, 3)).(color.Gray).Y; got != 50 {
+		t.Errorf("page 1: gray %d, want 50", got)
+	}
+	anim, err = decodeAnimation(bytes.NewReader(data), decodeOptions{page: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.frames) != 2 || anim.frames[0] == nil || anim.frames[1] == nil {
+		t.Errorf("every page: got frames %v, want both", anim.frames)
+	}
+
+	// A thumbnail in the first IFD isn't taken for the page.
+	thumbFirst, _ := grayTIFF(tiffTestPage{w: 2, h: 1, reduced: true}, small)
+	if anim, err = decodeAnimation(bytes.NewReader(thumbFirst), decodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.frames) != 1 || anim.frames[0].Bounds() != image.Rect(0, 0, 4, 3) {
+		t.Errorf("thumbnail first: got %d frames, the first %v, want one of 4x3", len(anim.frames), anim.frames[0].Bounds())
+	}
+
+	// A page over the limit fails before it is decoded, but only if it is
+	// asked for.
+	limit := decodeOptions{maxPixels: 100}
+	if _, err := decodeAnimation(bytes.NewReader(data), limit); err != nil {
+		t.Errorf("page 0 under the limit: %v", err)
+	}
+	limit.page = 1
+	_, err = decodeAnimation(bytes.NewReader(data), limit)
+	var pe *pixelLimitError
+	if !errors.As(err, &pe) {
+		t.Fatalf("page 1 over the limit: got %v, want a *pixelLimitError", err)
+	}
+	if pe.width != 40 || pe.height != 30 {
+		t.Errorf("page 1 over the limit: got %dx%d, want 40x30", pe.width, pe.height)
+	}
+}
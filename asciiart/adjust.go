@@ -0,0 +1,95 @@
+package asciiart
+
+import (
+	"math"
+	"sort"
+)
+
+// sharpenKernel is the center-weighted 3x3 blur, summing to 16, that sharpen
+// subtracts; indexed by [dy+1][dx+1].
+var sharpenKernel = [3][3]float64{{1, 2, 1}, {2, 4, 2}, {1, 2, 1}}
+
+// sharpen applies an unsharp mask to the w x h luminance grid lums (0..255)
+// in place: each cell moves away from the blur of its neighborhood by amount
+// times the difference, clamped to 0..255. The blur clamps the kernel at the
+// borders. An amount of 0 leaves lums unchanged.
+func sharpen(lums []float64, w, h int, amount float64) {
+	if amount == 0 || len(lums) == 0 {
+		return
+	}
+	src := append([]float64(nil), lums...)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var blur float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					sx := min(max(x+dx, 0), w-1)
+					sy := min(max(y+dy, 0), h-1)
+					blur += sharpenKernel[dy+1][dx+1] * src[sy*w+sx]
+				}
+			}
+			v := src[y*w+x]
+			lums[y*w+x] = math.Max(0, math.Min(255, v+amount*(v-blur/16)))
+		}
+	}
+}
+
+// stretchContrast linearly remaps the luminance grid lums (0..255) in place
+// so its darkest value becomes 0 and its brightest 255. clip is the
+// percentage of cells at each end treated as outliers: the range is taken
+// between those percentiles and the outliers saturate. A flat grid is left
+// unchanged.
+func stretchContrast(lums []float64, clip float64) {
+	if len(lums) == 0 {
+		return
+	}
+	sorted := append([]float64(nil), lums...)
+	sort.Float64s(sorted)
+	k := int(clip / 100 * float64(len(sorted)))
+	if k > (len(sorted)-1)/2 {
+		k = (len(sorted) - 1) / 2
+	}
+	lo, hi := sorted[k], sorted[len(sorted)-1-k]
+	if hi <= lo {
+		return
+	}
+	scale := 255 / (hi - lo)
+	for i, v := range lums {
+		lums[i] = math.Max(0, math.Min(255, (v-lo)*scale))
+	}
+}
+
+// equalizeHistogram remaps the luminance grid lums (0..255) in place through
+// its cumulative distribution, spreading the tones that occur most over more
+// of the range. The darkest tone present maps to 0 and the brightest to 255.
+// A flat grid is left unchanged.
+func equalizeHistogram(lums []float64) {
+	var hist [256]int
+	for _, v := range lums {
+		hist[lumBin(v)]++
+	}
+	var cdf [256]int
+	sum, cdfMin := 0, 0
+	for i, n := range hist {
+		sum += n
+		cdf[i] = sum
+		if cdfMin == 0 {
+			cdfMin = sum
+		}
+	}
+	if sum == cdfMin {
+		return
+	}
+	var lut [256]float64
+	for i, c := range cdf {
+		lut[i] = math.Max(0, math.Round(float64(c-cdfMin)*255/float64(sum-cdfMin)))
+	}
+	for i, v := range lums {
+		lums[i] = lut[lumBin(v)]
+	}
+}
+
+// lumBin returns the histogram bin, 0..255, of luminance v.
+func lumBin(v float64) int {
+	return int(math.Max(0, math.Min(255, math.Round(v))))
+}
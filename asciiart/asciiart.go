@@ -0,0 +1,459 @@
+// Package asciiart renders images as grids of text characters: glyphs from a
+// luminance ramp, Unicode Braille dots, or colored half blocks.
+//
+// The renderers take any image.Image, such as one already decoded or captured
+// in memory, and read it through At and RGBA, so grayscale, paletted, YCbCr
+// and straight- or premultiplied-alpha images need no conversion first.
+package asciiart
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultCharset is the built-in glyph ramp, from dark to light.
+const DefaultCharset = "@%#*+=-:. "
+
+// DefaultBWChars is the dark and light glyph pair used by Options.BW.
+const DefaultBWChars = "# "
+
+// DefaultCharAspect is the assumed width-to-height ratio of a terminal
+// character cell.
+const DefaultCharAspect = 0.5
+
+// Rec709 holds the Rec. 709 luma weights for the red, green, and blue
+// channels, the default Options.LumWeights.
+var Rec709 = [3]float64{0.2126, 0.7152, 0.0722}
+
+// SampleMode selects how each output cell's luminance is taken from the
+// source pixels it covers.
+type SampleMode int
+
+const (
+	SampleNearest SampleMode = iota // a single pixel per cell
+	SampleAverage                   // mean luminance, and for Render the mean color, over the cell's rectangle
+	SampleLanczos                   // the image Lanczos-resampled to one pixel per cell
+)
+
+// GrayMode selects how a pixel's luminance is read from its channels.
+type GrayMode int
+
+const (
+	GrayWeighted GrayMode = iota // channels weighted by Options.LumWeights
+	GrayMean                     // the plain mean of red, green and blue
+	GrayRed                      // the red channel alone
+	GrayGreen                    // the green channel alone
+	GrayBlue                     // the blue channel alone
+	GrayAlpha                    // 255 minus the alpha, ignoring color and Background, so opaque pixels take the darkest, densest glyph and transparent ones the lightest
+)
+
+// Options controls rendering. At least one of Width and Height must be set;
+// the zero value of every other field selects a sensible default.
+type Options struct {
+	Width      int        // output columns; 0 derives it from Height, the image and CharAspect
+	Height     int        // output rows; 0 derives it from Width, the image and CharAspect
+	CharAspect float64    // character cell width / height; 0 means DefaultCharAspect (smaller = fewer rows)
+	Invert     bool       // reverse the ramp (Render) or the dot test (RenderBraille)
+	Charset    string     // dark to light, at least 2 runes; empty means DefaultCharset
+	Gamma      float64    // luminance gamma, >1 brightens midtones; 0 means 1
+	Contrast   float64    // luminance multiplier around the 128 midpoint, applied after Gamma; 0 means 1
+	Brightness float64    // added to luminance after Contrast, -255..255
+	Sample     SampleMode // how source pixels are downsampled
+	Dither     bool       // Floyd-Steinberg error diffusion across the ramp levels
+	Bayer      int        // ordered dithering with a Bayer matrix of this size, 2, 4 or 8, instead of Dither; 0 disables it
+	BW         bool       // Render: two glyphs, BWChars, split at Threshold instead of the ramp
+	BWChars    string     // dark then light glyph for BW; empty means DefaultBWChars
+	Threshold  uint8      // pixels darker than this set a Braille dot, or take the dark BW glyph
+	LumWeights [3]float64 // R, G, B weights for luminance, normalized to sum to 1; all zero means Rec709
+	Gray       GrayMode   // how luminance is read from the channels; other than GrayWeighted, LumWeights must be zero
+	Background color.RGBA // translucent pixels are composited over this before luminance; alpha is ignored
+	Jobs       int        // rows rendered concurrently; 0 means runtime.NumCPU()
+
+	// TransparentSpace draws a space for any cell whose alpha, averaged over
+	// the cell when Sample is SampleAverage, is below AlphaThreshold. For an
+	// *image.Paletted, palette entries below AlphaThreshold are transparent,
+	// and an averaged cell is blank when most of its pixels are; otherwise
+	// only its opaque pixels count toward its luminance.
+	TransparentSpace bool
+	AlphaThreshold   uint8
+
+	// Blur is the standard deviation, in source pixels, of a Gaussian blur
+	// applied to the image before it is sampled, smoothing out noise; 0
+	// disables it.
+	Blur float64
+
+	// Tone is a tone curve, at least 2 points with rising In, applied to
+	// luminance right after Gamma and before Contrast. Nil leaves
+	// luminance alone.
+	Tone []TonePoint
+
+	// Perceptual measures luminance as CIE L*, from the LumWeights sum of
+	// linearized sRGB channels, instead of weighting the gamma-encoded
+	// channels directly. L* is perceptually even, so midtones spread over
+	// more of the ramp. It only applies under GrayWeighted.
+	Perceptual bool
+
+	// Supersample takes an evenly spread n x n lattice of pixels in each
+	// cell under SampleNearest (Render only) and uses their mean luminance
+	// and color, smoothing the noise of a single sample at n*n times its
+	// cost. 0 and 1 take the one pixel under the cell's center.
+	Supersample int
+
+	// Sharpen is the strength of an unsharp mask applied to the sampled
+	// luminance grid (Render only), restoring edges that downscaling blurs.
+	// It runs after Gamma, Contrast and Brightness; 0 disables it.
+	Sharpen float64
+
+	// AutoContrast stretches the sampled luminance range to 0..255 before
+	// glyphs are picked (Render only), after Gamma, Contrast and Brightness.
+	// AutoContrastClip is the percentage, 0..50, of darkest and of brightest
+	// cells ignored as outliers when finding the range.
+	AutoContrast     bool
+	AutoContrastClip float64
+
+	// Equalize remaps the sampled luminance through its cumulative histogram
+	// (Render only). It runs last, after AutoContrast; since it depends only
+	// on the order of tones, it largely supersedes the adjustments before it.
+	Equalize bool
+
+	// FlipY emits Render's rows bottom to top, for images stored with a
+	// bottom-left origin, as OpenGL reads them back. It mirrors whole rows
+	// of glyphs, so the other renderers, whose glyphs have their own
+	// vertical shape, ignore it.
+	FlipY bool
+
+	// Levels limits Render to that many glyphs, at least 2, spread evenly
+	// over the ramp from its first glyph to its last. 0, or more levels than
+	// the ramp has glyphs, uses the whole ramp.
+	Levels int
+
+	// EdgeThreshold is the minimum Sobel gradient magnitude, on the 0..255
+	// luminance scale, that RenderEdges draws as an edge.
+	EdgeThreshold float64
+
+	// Timings, when set, receives how long Render, RenderTo and RenderGrid
+	// spent sampling the image and picking glyphs. The other renderers
+	// leave it alone.
+	Timings *Timings
+
+	lum        *lumTable        // built from LumWeights by withDefaults
+	perceptual *perceptualTable // built from LumWeights by withDefaults under Perceptual
+	tone       *toneTable       // built from Tone by withDefaults
+}
+
+// withDefaults validates o and fills in its zero-valued defaults.
+func (o Options) withDefaults() (Options, error) {
+	if o.Width < 0 || o.Height < 0 {
+		return o, errors.New("asciiart: width and height must be >= 0")
+	}
+	if o.Width == 0 && o.Height == 0 {
+		return o, errors.New("asciiart: width or height must be > 0")
+	}
+	if o.CharAspect == 0 {
+		o.CharAspect = DefaultCharAspect
+	}
+	if o.CharAspect < 0 {
+		return o, errors.New("asciiart: char aspect must be > 0")
+	}
+	if o.Charset == "" {
+		o.Charset = DefaultCharset
+	}
+	if utf8.RuneCountInString(o.Charset) < 2 {
+		return o, errors.New("asciiart: charset must contain at least 2 characters")
+	}
+	if o.BWChars == "" {
+		o.BWChars = DefaultBWChars
+	}
+	if utf8.RuneCountInString(o.BWChars) != 2 {
+		return o, errors.New("asciiart: BW chars must be exactly 2 characters")
+	}
+	if o.Gamma == 0 {
+		o.Gamma = 1
+	}
+	if o.Gamma < 0 {
+		return o, errors.New("asciiart: gamma must be > 0")
+	}
+	if o.Contrast == 0 {
+		o.Contrast = 1
+	}
+	if o.Contrast < 0 {
+		return o, errors.New("asciiart: contrast must be > 0")
+	}
+	if o.Brightness < -255 || o.Brightness > 255 {
+		return o, errors.New("asciiart: brightness must be between -255 and 255")
+	}
+	if o.Gray < GrayWeighted || o.Gray > GrayAlpha {
+		return o, errors.New("asciiart: unknown gray mode")
+	}
+	if o.Gray != GrayWeighted && o.LumWeights != [3]float64{} {
+		return o, errors.New("asciiart: LumWeights and Gray cannot be combined")
+	}
+	if o.LumWeights == [3]float64{} {
+		o.LumWeights = Rec709
+	} else {
+		w, err := NormalizeWeights(o.LumWeights)
+		if err != nil {
+			return o, err
+		}
+		o.LumWeights = w
+	}
+	o.lum = newLumTable(o.LumWeights)
+	if o.Perceptual {
+		if o.Gray != GrayWeighted {
+			return o, errors.New("asciiart: Perceptual and Gray cannot be combined")
+		}
+		o.perceptual = newPerceptualTable(o.LumWeights)
+	}
+	if o.Tone != nil {
+		t, err := newToneTable(o.Tone)
+		if err != nil {
+			return o, err
+		}
+		o.tone = t
+	}
+	if o.Supersample == 0 {
+		o.Supersample = 1
+	}
+	if o.Supersample < 0 {
+		return o, errors.New("asciiart: supersample must be >= 0")
+	}
+	if o.Supersample > 1 && o.Sample != SampleNearest {
+		return o, errors.New("asciiart: Supersample needs SampleNearest")
+	}
+	if o.Blur < 0 {
+		return o, errors.New("asciiart: blur must be >= 0")
+	}
+	if o.Sharpen < 0 {
+		return o, errors.New("asciiart: sharpen must be >= 0")
+	}
+	if o.AutoContrastClip < 0 || o.AutoContrastClip > 50 {
+		return o, errors.New("asciiart: auto-contrast clip must be between 0 and 50")
+	}
+	if o.Bayer != 0 && o.Bayer != 2 && o.Bayer != 4 && o.Bayer != 8 {
+		return o, errors.New("asciiart: Bayer matrix size must be 2, 4 or 8")
+	}
+	if o.Bayer != 0 && o.Dither {
+		return o, errors.New("asciiart: Dither and Bayer cannot be combined")
+	}
+	if o.Levels < 0 || o.Levels == 1 {
+		return o, errors.New("asciiart: levels must be 0 or at least 2")
+	}
+	if o.EdgeThreshold < 0 {
+		return o, errors.New("asciiart: edge threshold must be >= 0")
+	}
+	if o.Jobs < 0 {
+		return o, errors.New("asciiart: jobs must be >= 0")
+	}
+	return o, nil
+}
+
+// size returns the output grid for img. A zero Width or Height is derived
+// from the other and the image's aspect ratio, given that a character cell is
+// cellAspect times as wide as it is tall. Nothing is derived for an empty
+// image.
+func (o Options) size(img image.Image, cellAspect float64) (cols, rows int) {
+	cols, rows = o.Width, o.Height
+	b := img.Bounds()
+	if b.Empty() {
+		return cols, rows
+	}
+	if rows == 0 {
+		rows = int(math.Max(1, math.Round(float64(b.Dy())*cellAspect*float64(cols)/float64(b.Dx()))))
+	}
+	if cols == 0 {
+		cols = int(math.Max(1, math.Round(float64(b.Dx())*float64(rows)/(float64(b.Dy())*cellAspect))))
+	}
+	return cols, rows
+}
+
+// Render maps img onto rows of glyphs chosen from the luminance ramp.
+func Render(img image.Image, opts Options) ([]string, error) {
+	var rows []string
+	err := renderRows(img, opts, func(row string) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// RenderTo is like Render but writes each row to w, followed by a newline,
+// as soon as it is picked, so only the luminance grid and one row of text
+// are held at a time. When w has a Flush method, as a *bufio.Writer does,
+// it is flushed after every row.
+func RenderTo(img image.Image, w io.Writer, opts Options) error {
+	flusher, _ := w.(interface{ Flush() error })
+	var line []byte
+	return renderRows(img, opts, func(row string) error {
+		line = append(append(line[:0], row...), '\n')
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if flusher != nil {
+			return flusher.Flush()
+		}
+		return nil
+	})
+}
+
+// renderRows renders img like Render, passing each row's text to emit in
+// order and stopping at the first error it returns.
+func renderRows(img image.Image, opts Options, emit func(row string) error) error {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
+	cols, rows := opts.size(img, opts.CharAspect)
+	start := time.Now()
+	r, err := newASCIIRenderer(prepare(img, cols, rows, opts), cols, rows, opts)
+	if err != nil {
+		return err
+	}
+	opts.Timings.addSample(start)
+	start = time.Now()
+	defer opts.Timings.addGlyphs(start)
+	cells := make([]Cell, cols)
+	text := make([]rune, cols)
+	for i := 0; i < rows; i++ {
+		y := i
+		if opts.FlipY {
+			y = rows - 1 - i
+		}
+		r.row(y, cells)
+		for x, c := range cells {
+			text[x] = c.Ch
+		}
+		if err := emit(string(text)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderGrid is like Render but keeps the color each glyph was sampled from.
+func RenderGrid(img image.Image, opts Options) (Grid, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	cols, rows := opts.size(img, opts.CharAspect)
+	start := time.Now()
+	img = prepare(img, cols, rows, opts)
+	opts.Timings.addSample(start)
+	return renderASCII(img, cols, rows, opts)
+}
+
+// LuminanceGrid returns the luminance, 0..255, by which Render picks each
+// glyph, indexed by [row][column]: the sampled grid after Gamma, Tone,
+// Contrast, Brightness, Sharpen, AutoContrast and Equalize, but before Dither
+// or Bayer. Rows are in Render's order, bottom first under FlipY.
+func LuminanceGrid(img image.Image, opts Options) ([][]uint8, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	opts.Dither = false
+	cols, rows := opts.size(img, opts.CharAspect)
+	r, err := newASCIIRenderer(prepare(img, cols, rows, opts), cols, rows, opts)
+	if err != nil {
+		return nil, err
+	}
+	g := make([][]uint8, rows)
+	for y := range g {
+		g[y] = make([]uint8, cols)
+		for x := range g[y] {
+			g[y][x] = uint8(math.Round(r.lums[y*cols+x]))
+		}
+	}
+	if opts.FlipY {
+		slices.Reverse(g)
+	}
+	return g, nil
+}
+
+// RenderBraille maps img onto a grid of Braille characters, each covering a
+// 2x4 block of dots.
+func RenderBraille(img image.Image, opts Options) (Grid, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	cols, rows := opts.size(img, opts.CharAspect)
+	return renderBraille(prepare(img, 2*cols, 4*rows, opts), cols, rows, opts)
+}
+
+// RenderHalfBlock maps img onto a grid of half-block characters, each showing
+// two vertically stacked pixels as its foreground and background colors.
+// A zero Width or Height is derived taking the pixels to be square.
+func RenderHalfBlock(img image.Image, opts Options) (Grid, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	// Each character holds two square pixels stacked vertically.
+	cols, rows := opts.size(img, 0.5)
+	if b := img.Bounds(); opts.Height == 0 && !b.Empty() {
+		// Size the pixel grid first and use half as many text rows, rounding
+		// up so an odd last pixel row still gets a character.
+		pixelRows := int(math.Max(1, math.Round(float64(b.Dy())*float64(cols)/float64(b.Dx()))))
+		rows = (pixelRows + 1) / 2
+	}
+	return renderHalfBlock(prepare(img, cols, 2*rows, opts), cols, rows, opts.Jobs)
+}
+
+// RenderEdges maps img onto a grid of outline characters ('|', '-', '/' and
+// '\') drawn where the luminance gradient exceeds opts.EdgeThreshold.
+func RenderEdges(img image.Image, opts Options) (Grid, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	cols, rows := opts.size(img, opts.CharAspect)
+	return renderEdges(prepare(img, cols, rows, opts), cols, rows, opts)
+}
+
+// prepare applies the source preprocessing in opts to img for a w x h
+// sampling grid: CMYK to RGB conversion, Blur, then Lanczos resampling.
+func prepare(img image.Image, w, h int, opts Options) image.Image {
+	return resample(blurImage(toRGB(img), opts.Blur, opts.Jobs), w, h, opts.Sample)
+}
+
+// NormalizeWeights scales w so its components sum to 1. It reports an error
+// when a weight is negative or all of them are zero.
+func NormalizeWeights(w [3]float64) ([3]float64, error) {
+	sum := 0.0
+	for _, v := range w {
+		if v < 0 {
+			return w, errors.New("asciiart: luminance weights must not be negative")
+		}
+		sum += v
+	}
+	if sum == 0 {
+		return w, errors.New("asciiart: luminance weights must not all be zero")
+	}
+	for i := range w {
+		w[i] /= sum
+	}
+	return w, nil
+}
+
+// checkGrid reports an error unless img has pixels and the newW x newH output
+// grid is non-empty.
+func checkGrid(img image.Image, newW, newH int) error {
+	if b := img.Bounds(); b.Empty() {
+		return fmt.Errorf("asciiart: image has zero dimension (%dx%d)", b.Dx(), b.Dy())
+	}
+	if newW <= 0 || newH <= 0 {
+		return fmt.Errorf("asciiart: output grid must be at least 1x1, got %dx%d", newW, newH)
+	}
+	return nil
+}
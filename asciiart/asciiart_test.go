@@ -0,0 +1,1187 @@
+package asciiart
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gradient returns a w x h image fading from black on the left to white on
+// the right.
+func gradient(w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{uint8(x * 255 / (w - 1))})
+		}
+	}
+	return img
+}
+
+func TestRenderGradient(t *testing.T) {
+	rows, err := Render(gradient(10, 4), Options{Width: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 4 * 0.5 * 10 / 10 = 2 rows.
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	want := "@%#*+=-:. "
+	for i, row := range rows {
+		if row != want {
+			t.Errorf("row %d = %q, want %q", i, row, want)
+		}
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	timings := &Timings{}
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want Options
+	}{
+		{"defaults", nil, Options{Width: DefaultWidth}},
+		{"height alone", []Option{WithHeight(10)}, Options{Height: 10}},
+		{"width and height", []Option{WithWidth(20), WithHeight(10)}, Options{Width: 20, Height: 10}},
+		{"later wins", []Option{WithWidth(20), WithGamma(2), WithWidth(30)}, Options{Width: 30, Gamma: 2}},
+		{"bayer after dither", []Option{WithDither(true), WithBayer(4)}, Options{Width: DefaultWidth, Bayer: 4}},
+		{"dither after bayer", []Option{WithBayer(4), WithDither(true)}, Options{Width: DefaultWidth, Dither: true}},
+		{"gray after weights", []Option{WithLumWeights([3]float64{1, 0, 0}), WithGray(GrayMean)}, Options{Width: DefaultWidth, Gray: GrayMean}},
+		{"weights after gray", []Option{WithGray(GrayMean), WithLumWeights([3]float64{1, 0, 0})}, Options{Width: DefaultWidth, LumWeights: [3]float64{1, 0, 0}}},
+		{"bw", []Option{WithBW(true), WithBWChars("X."), WithThreshold(100)}, Options{Width: DefaultWidth, BW: true, BWChars: "X.", Threshold: 100}},
+		{"contrast stretch", []Option{WithAutoContrast(true), WithAutoContrastClip(2), WithEqualize(true)}, Options{Width: DefaultWidth, AutoContrast: true, AutoContrastClip: 2, Equalize: true}},
+		{"filters", []Option{WithBlur(1.5), WithSharpen(0.5)}, Options{Width: DefaultWidth, Blur: 1.5, Sharpen: 0.5}},
+		{"alpha", []Option{WithTransparentSpace(true), WithAlphaThreshold(64)}, Options{Width: DefaultWidth, TransparentSpace: true, AlphaThreshold: 64}},
+		{"rows and edges", []Option{WithFlipY(true), WithEdgeThreshold(40)}, Options{Width: DefaultWidth, FlipY: true, EdgeThreshold: 40}},
+		{"timings", []Option{WithTimings(timings)}, Options{Width: DefaultWidth, Timings: timings}},
+	} {
+		if got := NewOptions(tc.opts...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+
+	// RenderWith draws what Render does with the same settings, and the
+	// combinations NewOptions resolves are valid.
+	img := gradient(40, 20)
+	for _, opts := range [][]Option{
+		{WithWidth(10), WithInvert(true), WithCharset(" .:#"), WithGamma(2.2)},
+		{WithHeight(4), WithSample(SampleAverage), WithContrast(1.5), WithBrightness(-20)},
+		{WithWidth(12), WithDither(true), WithBayer(8), WithLevels(3)},
+		{WithWidth(12), WithLumWeights([3]float64{0, 1, 0}), WithGray(GrayRed), WithSupersample(3)},
+		{WithWidth(12), WithBW(true), WithBWChars("X."), WithThreshold(90), WithFlipY(true)},
+		{WithWidth(12), WithBlur(1), WithSharpen(1), WithAutoContrast(true), WithAutoContrastClip(5), WithEqualize(true)},
+	} {
+		got, err := RenderWith(img, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := Render(img, NewOptions(opts...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("RenderWith(%+v) = %q, want %q", NewOptions(opts...), got, want)
+		}
+	}
+	rows, err := RenderWith(img, WithWidth(10), WithHeight(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || len(rows[0]) != 10 {
+		t.Errorf("width 10, height 3: got %d rows of %d", len(rows), len(rows[0]))
+	}
+}
+
+func TestRenderImageTypes(t *testing.T) {
+	// The same gradient in each in-memory representation renders the same.
+	src := gradient(10, 4)
+	ycc := image.NewYCbCr(src.Bounds(), image.YCbCrSubsampleRatio444)
+	copy(ycc.Y, src.Pix)
+	for i := range ycc.Cb {
+		ycc.Cb[i], ycc.Cr[i] = 128, 128
+	}
+	gray16 := image.NewGray16(src.Bounds())
+	rgba := image.NewRGBA(src.Bounds())
+	nrgba := image.NewNRGBA(src.Bounds())
+	nrgba64 := image.NewNRGBA64(src.Bounds())
+	grays := make(color.Palette, 256)
+	for i := range grays {
+		grays[i] = color.Gray{uint8(i)}
+	}
+	paletted := image.NewPaletted(src.Bounds(), grays)
+	for _, dst := range []draw.Image{gray16, rgba, nrgba, nrgba64, paletted} {
+		draw.Draw(dst, dst.Bounds(), src, image.Point{}, draw.Src)
+	}
+	for _, sample := range []SampleMode{SampleNearest, SampleAverage} {
+		want, err := Render(src, Options{Width: 10, Sample: sample})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, img := range []image.Image{gray16, rgba, nrgba, nrgba64, paletted, ycc} {
+			got, err := Render(img, Options{Width: 10, Sample: sample})
+			if err != nil {
+				t.Fatalf("%T: %v", img, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%T, sample %v: got %q, want %q as for *image.Gray", img, sample, got, want)
+			}
+		}
+	}
+}
+
+func TestLuminanceGrid(t *testing.T) {
+	g, err := LuminanceGrid(gradient(4, 2), Options{Width: 4, Height: 1, Dither: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The source values themselves; Dither doesn't apply.
+	if want := [][]uint8{{0, 85, 170, 255}}; !reflect.DeepEqual(g, want) {
+		t.Errorf("got %v, want %v", g, want)
+	}
+	g, err = LuminanceGrid(gradient(4, 2), Options{Width: 4, Height: 1, Invert: true, Brightness: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Brightness applies, but Invert only reverses the ramp.
+	if want := [][]uint8{{10, 95, 180, 255}}; !reflect.DeepEqual(g, want) {
+		t.Errorf("brightness 10: got %v, want %v", g, want)
+	}
+}
+
+// flushCounter records what is written to it and how often it is flushed.
+type flushCounter struct {
+	strings.Builder
+	flushes int
+}
+
+func (f *flushCounter) Flush() error {
+	f.flushes++
+	return nil
+}
+
+func TestRenderTo(t *testing.T) {
+	img := gradient(40, 20)
+	opts := Options{Width: 12, Dither: true}
+	rows, err := Render(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out flushCounter
+	if err := RenderTo(img, &out, opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(rows, "\n") + "\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+	if out.flushes != len(rows) {
+		t.Errorf("flushed %d times, want once per row (%d)", out.flushes, len(rows))
+	}
+
+	if err := RenderTo(img, &out, Options{}); err == nil {
+		t.Error("RenderTo with no size succeeded, want error")
+	}
+}
+
+func TestRenderTimings(t *testing.T) {
+	var tm Timings
+	opts := Options{Width: 16, Timings: &tm}
+	if _, err := RenderGrid(gradient(64, 64), opts); err != nil {
+		t.Fatal(err)
+	}
+	if tm.Sample <= 0 || tm.Glyphs <= 0 {
+		t.Fatalf("after RenderGrid: %+v, want both phases timed", tm)
+	}
+	first := tm
+	if err := RenderTo(gradient(64, 64), io.Discard, opts); err != nil {
+		t.Fatal(err)
+	}
+	if tm.Sample <= first.Sample || tm.Glyphs <= first.Glyphs {
+		t.Errorf("after RenderTo: %+v, want more than %+v", tm, first)
+	}
+}
+
+func TestRenderFlipY(t *testing.T) {
+	// Black over white: under FlipY the white row comes out on top.
+	img := image.NewGray(image.Rect(0, 0, 1, 2))
+	img.SetGray(0, 1, color.Gray{255})
+	opts := Options{Width: 1, Height: 2}
+	rows, err := Render(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"@", " "}; !reflect.DeepEqual(rows, want) {
+		t.Fatalf("unflipped: got %q, want %q", rows, want)
+	}
+	opts.FlipY = true
+	if rows, err = Render(img, opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{" ", "@"}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("Render: got %q, want %q", rows, want)
+	}
+	g, err := RenderGrid(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{" ", "@"}; !reflect.DeepEqual(g.Text(), want) {
+		t.Errorf("RenderGrid: got %q, want %q", g.Text(), want)
+	}
+	lums, err := LuminanceGrid(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]uint8{{255}, {0}}; !reflect.DeepEqual(lums, want) {
+		t.Errorf("LuminanceGrid: got %v, want %v", lums, want)
+	}
+}
+
+func TestRenderInvert(t *testing.T) {
+	rows, err := Render(gradient(10, 4), Options{Width: 10, Invert: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := " .:-=+*#%@"; rows[0] != want {
+		t.Errorf("got %q, want %q", rows[0], want)
+	}
+}
+
+func TestRenderCustomCharset(t *testing.T) {
+	rows, err := Render(gradient(100, 40), Options{Width: 4, Charset: "█░"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "██░░"; rows[0] != want {
+		t.Errorf("got %q, want %q", rows[0], want)
+	}
+}
+
+func TestRenderSize(t *testing.T) {
+	img := gradient(100, 50)
+	for _, tc := range []struct {
+		opts       Options
+		cols, rows int
+	}{
+		{Options{Width: 40}, 40, 10},
+		{Options{Height: 10}, 40, 10},
+		{Options{Height: 10, CharAspect: 1}, 20, 10},
+		{Options{Width: 7, Height: 3}, 7, 3},
+	} {
+		g, err := RenderGrid(img, tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(g) != tc.rows || len(g[0]) != tc.cols {
+			t.Errorf("%+v: got %dx%d, want %dx%d", tc.opts, len(g[0]), len(g), tc.cols, tc.rows)
+		}
+	}
+}
+
+func TestRenderAverageSample(t *testing.T) {
+	// A 1px white line on black vanishes under nearest sampling but still
+	// brightens its cell when averaged.
+	img := image.NewGray(image.Rect(0, 0, 40, 4))
+	for y := 0; y < 4; y++ {
+		img.SetGray(2, y, color.Gray{255})
+	}
+	opts := Options{Width: 4, Height: 1}
+	nearest, err := Render(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Sample = SampleAverage
+	average, err := Render(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nearest[0] != "@@@@" {
+		t.Errorf("nearest = %q, want %q", nearest[0], "@@@@")
+	}
+	if average[0][0] == '@' {
+		t.Errorf("average = %q, want a lighter first cell", average[0])
+	}
+}
+
+func TestRenderAverageColor(t *testing.T) {
+	// A red and a blue pixel share one cell: nearest sampling colors it with
+	// the blue one, averaging with their mix.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{0, 0, 255, 255})
+	for _, tc := range []struct {
+		sample SampleMode
+		want   color.RGBA
+	}{
+		{SampleNearest, color.RGBA{0, 0, 255, 255}},
+		{SampleAverage, color.RGBA{128, 0, 128, 255}},
+	} {
+		g, err := RenderGrid(img, Options{Width: 1, Height: 1, Sample: tc.sample})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g[0][0].FG; got != tc.want {
+			t.Errorf("sample %d: color = %v, want %v", tc.sample, got, tc.want)
+		}
+	}
+}
+
+func TestRenderSupersample(t *testing.T) {
+	// A cell half red, half blue: one sample takes the blue under its
+	// center, a 2x2 lattice an even mix of both halves.
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{0, 0, 255, 255}
+			if x < 2 {
+				c = color.RGBA{255, 0, 0, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	for _, tc := range []struct {
+		n    int
+		want color.RGBA
+	}{
+		{1, color.RGBA{0, 0, 255, 255}},
+		{2, color.RGBA{128, 0, 128, 255}},
+		{4, color.RGBA{128, 0, 128, 255}},
+	} {
+		g, err := RenderGrid(img, Options{Width: 1, Height: 1, Supersample: tc.n})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g[0][0].FG; got != tc.want {
+			t.Errorf("supersample %d: color = %v, want %v", tc.n, got, tc.want)
+		}
+	}
+
+	// More samples than pixels, on an image away from the origin, stay
+	// inside it.
+	sub := img.SubImage(image.Rect(1, 1, 3, 2))
+	if _, err := Render(sub, Options{Width: 5, Height: 3, Supersample: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Render(img, Options{Width: 1, Supersample: 2, Sample: SampleAverage}); err == nil {
+		t.Error("Supersample with SampleAverage: want an error")
+	}
+}
+
+func TestRenderLanczosSample(t *testing.T) {
+	// Like averaging, resampling keeps a thin line nearest sampling skips,
+	// and a flat image stays flat.
+	img := image.NewGray(image.Rect(0, 0, 40, 4))
+	for y := 0; y < 4; y++ {
+		img.SetGray(2, y, color.Gray{255})
+	}
+	rows, err := Render(img, Options{Width: 4, Height: 1, Sample: SampleLanczos})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0][0] == '@' || rows[0][1:] != "@@@" {
+		t.Errorf("line: got %q, want a lighter first cell then %q", rows[0], "@@@")
+	}
+	flat := image.NewGray(image.Rect(0, 0, 100, 50))
+	for i := range flat.Pix {
+		flat.Pix[i] = 128
+	}
+	want, err := Render(flat, Options{Width: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Render(flat, Options{Width: 7, Sample: SampleLanczos})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("flat: got %q, want %q", got, want)
+	}
+}
+
+func TestRenderNearestCentered(t *testing.T) {
+	// A 6x6 checkerboard of 2x2 squares, white at the corners, is the same
+	// mirrored either way, and so should its 2x2 rendering be. Sampling each
+	// cell's center reads the four corner squares; sampling each cell's
+	// top-left corner would read the lopsided top-left quarter.
+	img := image.NewGray(image.Rect(0, 0, 6, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			if (x/2+y/2)%2 == 0 {
+				img.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	rows, err := Render(img, Options{Width: 2, Height: 2, Charset: "@ "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"  ", "  "}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %q, want %q", rows, want)
+	}
+}
+
+func TestRenderGamma(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	img.SetGray(0, 0, color.Gray{64})
+	plain, err := Render(img, Options{Width: 1, Height: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bright, err := Render(img, Options{Width: 1, Height: 1, Gamma: 2.2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.IndexRune(DefaultCharset, rune(bright[0][0])) <= strings.IndexRune(DefaultCharset, rune(plain[0][0])) {
+		t.Errorf("gamma 2.2 gave %q, want lighter than %q", bright[0], plain[0])
+	}
+}
+
+func TestRenderParallelMatchesSerial(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 97, 61))
+	for y := 0; y < 61; y++ {
+		for x := 0; x < 97; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 7), uint8(y * 13), uint8(x * y), 0xff})
+		}
+	}
+	for _, opts := range []Options{
+		{Width: 37, Sample: SampleAverage},
+		{Width: 37, Dither: true},
+		{Width: 37, Threshold: 128},
+	} {
+		render := RenderGrid
+		if opts.Threshold != 0 {
+			render = RenderBraille
+		}
+		opts.Jobs = 1
+		serial, err := render(img, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Jobs = 8
+		parallel, err := render(img, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(serial, parallel) {
+			t.Errorf("%+v: parallel output differs from serial", opts)
+		}
+	}
+}
+
+func TestRenderAlphaComposite(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 255, 255, 255}) // opaque white
+	img.SetNRGBA(1, 0, color.NRGBA{255, 255, 255, 128}) // half-covered white
+	img.SetNRGBA(2, 0, color.NRGBA{255, 255, 255, 0})   // fully transparent
+	for _, tc := range []struct {
+		bg   color.RGBA
+		want string
+	}{
+		{color.RGBA{}, " =@"},
+		{color.RGBA{255, 255, 255, 255}, "   "},
+	} {
+		rows, err := Render(img, Options{Width: 3, Height: 1, Background: tc.bg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0] != tc.want {
+			t.Errorf("background %v: got %q, want %q", tc.bg, rows[0], tc.want)
+		}
+	}
+}
+
+func TestRenderTransparentSpace(t *testing.T) {
+	// Opaque black on the left half, transparent black on the right.
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 2; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{0, 0, 0, 255})
+		}
+	}
+	opts := Options{Width: 4, Height: 1, Background: color.RGBA{0, 0, 0, 255}, TransparentSpace: true, AlphaThreshold: 128}
+	rows, err := Render(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "@@  "; rows[0] != want {
+		t.Errorf("nearest: got %q, want %q", rows[0], want)
+	}
+
+	// Averaged over the whole image, alpha is 50%: just above the cutoff of
+	// 128 once rounded, and just below a cutoff of 129.
+	opts.Width, opts.Sample = 1, SampleAverage
+	if rows, _ = Render(img, opts); rows[0] != "@" {
+		t.Errorf("average, cutoff 128: got %q, want %q", rows[0], "@")
+	}
+	opts.AlphaThreshold = 129
+	if rows, _ = Render(img, opts); rows[0] != " " {
+		t.Errorf("average, cutoff 129: got %q, want %q", rows[0], " ")
+	}
+}
+
+func TestRenderPalettedTransparency(t *testing.T) {
+	// A GIF-style palette with a transparent index: two black pixels and a
+	// transparent one, then the reverse, over a white background.
+	img := image.NewPaletted(image.Rect(0, 0, 6, 1), color.Palette{color.RGBA{}, color.RGBA{0, 0, 0, 255}})
+	copy(img.Pix, []uint8{1, 1, 0, 0, 0, 1})
+	opts := Options{Width: 2, Height: 1, Sample: SampleAverage, Background: color.RGBA{255, 255, 255, 255}}
+	for _, tc := range []struct {
+		transparentSpace bool
+		sample           SampleMode
+		want             string
+	}{
+		// Without TransparentSpace the background shows through, as for
+		// any other image.
+		{false, SampleAverage, "*-"},
+		// Blank where most of a cell is transparent, and the rest solid.
+		{true, SampleAverage, "@ "},
+		{true, SampleNearest, "@ "},
+	} {
+		opts.TransparentSpace, opts.AlphaThreshold, opts.Sample = tc.transparentSpace, 128, tc.sample
+		rows, err := Render(img, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0] != tc.want {
+			t.Errorf("transparent space %v, sample %v: got %q, want %q", tc.transparentSpace, tc.sample, rows[0], tc.want)
+		}
+	}
+}
+
+func TestAdjustLuminance(t *testing.T) {
+	for _, tc := range []struct {
+		l                    uint8
+		contrast, brightness float64
+		want                 uint8
+	}{
+		{100, 1, 0, 100},
+		{100, 1.5, 0, 86},
+		{200, 1.5, 0, 236},
+		{200, 2, 0, 255},
+		{100, 1, 40, 140},
+		{100, 1, -120, 0},
+		// Contrast applies before brightness.
+		{100, 2, 50, 122},
+	} {
+		opts := Options{Gamma: 1, Contrast: tc.contrast, Brightness: tc.brightness}
+		if got := adjustLuminance(tc.l, opts); got != tc.want {
+			t.Errorf("adjustLuminance(%d, contrast %g, brightness %g) = %d, want %d", tc.l, tc.contrast, tc.brightness, got, tc.want)
+		}
+	}
+}
+
+func TestToneCurve(t *testing.T) {
+	// Shadows lifted, highlights left alone, with an output past 255
+	// clamped and inputs before the first point held flat.
+	opts, err := Options{Width: 1, Tone: []TonePoint{{20, 10}, {64, 128}, {128, 160}, {200, 300}}}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ l, want uint8 }{
+		{0, 10}, {20, 10}, {42, 69}, {64, 128}, {96, 144}, {164, 230}, {200, 255}, {255, 255},
+	} {
+		if got := adjustLuminance(tc.l, opts); got != tc.want {
+			t.Errorf("adjustLuminance(%d) = %d, want %d", tc.l, got, tc.want)
+		}
+	}
+	for _, pts := range [][]TonePoint{
+		{{0, 0}},
+		{{0, 0}, {128, 100}, {128, 200}},
+		{{0, 0}, {256, 255}},
+	} {
+		if _, err := (Options{Width: 1, Tone: pts}).withDefaults(); err == nil {
+			t.Errorf("tone %v: want an error", pts)
+		}
+	}
+}
+
+func TestRenderLumWeights(t *testing.T) {
+	// Pure red reads as dark under Rec. 709 but white when only red counts.
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	rows, err := Render(img, Options{Width: 1, Height: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0] != "#" {
+		t.Errorf("Rec. 709: got %q, want %q", rows[0], "#")
+	}
+	rows, err = Render(img, Options{Width: 1, Height: 1, LumWeights: [3]float64{2, 0, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0] != " " {
+		t.Errorf("red only: got %q, want %q", rows[0], " ")
+	}
+}
+
+func TestRenderGray(t *testing.T) {
+	// A pinkish red, to show each mode reading the channels its own way.
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.RGBA{200, 40, 100, 255})
+	for _, tc := range []struct {
+		mode GrayMode
+		want uint8
+	}{
+		{GrayWeighted, 78},
+		{GrayMean, 113},
+		{GrayRed, 200},
+		{GrayGreen, 40},
+		{GrayBlue, 100},
+	} {
+		g, err := LuminanceGrid(img, Options{Width: 1, Height: 1, Gray: tc.mode})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := g[0][0]; got != tc.want {
+			t.Errorf("mode %d: got %d, want %d", tc.mode, got, tc.want)
+		}
+	}
+	if _, err := Render(img, Options{Width: 1, Gray: GrayMean, LumWeights: Rec709}); err == nil {
+		t.Error("Gray with LumWeights: want an error")
+	}
+}
+
+func TestRenderPerceptual(t *testing.T) {
+	// L* 50 is sRGB gray 119, which linear luma leaves at 119; blue, dark
+	// by luma, reads as brighter by L*.
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	img.Set(1, 0, color.RGBA{119, 119, 119, 255})
+	img.Set(2, 0, color.RGBA{0, 0, 255, 255})
+	img.Set(3, 0, color.RGBA{255, 255, 255, 255})
+	for _, tc := range []struct {
+		perceptual bool
+		want       []uint8
+	}{
+		{false, []uint8{0, 119, 18, 255}},
+		{true, []uint8{0, 128, 82, 255}},
+	} {
+		g, err := LuminanceGrid(img, Options{Width: 4, Height: 1, Perceptual: tc.perceptual})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(g[0], tc.want) {
+			t.Errorf("perceptual %v: got %v, want %v", tc.perceptual, g[0], tc.want)
+		}
+	}
+	if _, err := Render(img, Options{Width: 1, Perceptual: true, Gray: GrayMean}); err == nil {
+		t.Error("Perceptual with Gray: want an error")
+	}
+}
+
+func TestRenderAlpha(t *testing.T) {
+	// Alpha alone sets the glyph, whatever the color: opaque draws the
+	// densest, transparent the lightest, half covered the middle.
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.Set(0, 0, color.NRGBA{255, 255, 255, 255})
+	img.Set(1, 0, color.NRGBA{0, 0, 0, 0})
+	img.Set(2, 0, color.NRGBA{200, 40, 100, 128})
+	rows, err := Render(img, Options{Width: 3, Height: 1, Charset: "@+ ", Gray: GrayAlpha, Background: color.RGBA{255, 255, 255, 255}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0] != "@ +" {
+		t.Errorf("got %q, want %q", rows[0], "@ +")
+	}
+}
+
+func TestRenderCMYK(t *testing.T) {
+	// An Adobe CMYK JPEG with two solid 8x8 patches: full cyan ink, then 50%
+	// black. Read as light rather than ink, both would come out near black.
+	f, err := os.Open("testdata/cmyk.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.CMYK); !ok {
+		t.Fatalf("decoded as %T, want *image.CMYK", img)
+	}
+	g, err := RenderGrid(img, Options{Width: 2, Height: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cyan has luminance 201 and the gray 127.
+	for i, want := range []struct {
+		ch rune
+		fg color.RGBA
+	}{
+		{':', color.RGBA{0, 255, 255, 255}},
+		{'+', color.RGBA{127, 127, 127, 255}},
+	} {
+		if c := g[0][i]; c.Ch != want.ch || c.FG != want.fg {
+			t.Errorf("patch %d: got %q %v, want %q %v", i, c.Ch, c.FG, want.ch, want.fg)
+		}
+	}
+}
+
+func TestNormalizeWeights(t *testing.T) {
+	w, err := NormalizeWeights([3]float64{1, 2, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [3]float64{0.25, 0.5, 0.25}; w != want {
+		t.Errorf("got %v, want %v", w, want)
+	}
+	for _, bad := range [][3]float64{{0, 0, 0}, {1, -1, 1}} {
+		if _, err := NormalizeWeights(bad); err == nil {
+			t.Errorf("NormalizeWeights(%v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestRenderBW(t *testing.T) {
+	img := gradient(10, 4)
+	for _, tc := range []struct {
+		opts Options
+		want string
+	}{
+		{Options{Threshold: 128}, "#####     "},
+		{Options{Threshold: 200}, "########  "},
+		{Options{Threshold: 128, Invert: true}, "     #####"},
+		{Options{Threshold: 128, BWChars: "X."}, "XXXXX....."},
+	} {
+		tc.opts.Width, tc.opts.Height, tc.opts.BW = 10, 1, true
+		rows, err := Render(img, tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0] != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.opts, rows[0], tc.want)
+		}
+	}
+}
+
+func TestRenderLevels(t *testing.T) {
+	img := gradient(10, 4)
+	for _, tc := range []struct {
+		opts Options
+		want string
+	}{
+		{Options{Levels: 2}, "@@@@@     "},
+		{Options{Levels: 4}, "@@***---  "},
+		{Options{Levels: 3, Charset: "abcd"}, "aaaccccddd"},
+		{Options{Levels: 20}, "@%#*+=-:. "},
+	} {
+		tc.opts.Width, tc.opts.Height = 10, 1
+		rows, err := Render(img, tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0] != tc.want {
+			t.Errorf("%+v: got %q, want %q", tc.opts, rows[0], tc.want)
+		}
+	}
+}
+
+func TestBlurImage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 9, 9))
+	if blurImage(img, 0, 1) != image.Image(img) {
+		t.Error("sigma 0 returned a new image")
+	}
+
+	// A single white pixel spreads out evenly, its total brightness kept.
+	img.SetGray(4, 4, color.Gray{255})
+	out := blurImage(img, 1, 1)
+	lum := func(x, y int) float64 {
+		r, _, _, _ := out.At(x, y).RGBA()
+		return float64(r)
+	}
+	total := 0.0
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			total += lum(x, y)
+		}
+	}
+	if math.Abs(total-0xffff) > 100 {
+		t.Errorf("total = %v, want about %v", total, 0xffff)
+	}
+	if lum(3, 4) != lum(5, 4) || lum(4, 3) != lum(4, 5) || lum(3, 4) != lum(4, 3) {
+		t.Errorf("blur is not symmetric around the pixel")
+	}
+	if !(lum(4, 4) > lum(3, 4) && lum(3, 4) > lum(2, 4) && lum(2, 4) > 0) {
+		t.Errorf("blur doesn't fall off from the center: %v, %v, %v", lum(4, 4), lum(3, 4), lum(2, 4))
+	}
+
+	// Edges are clamped, so a flat image stays flat.
+	flat := image.NewUniform(color.Gray{90})
+	sub := image.NewGray(image.Rect(2, 3, 7, 6))
+	draw.Draw(sub, sub.Bounds(), flat, image.Point{}, draw.Src)
+	out = blurImage(sub, 2, 2)
+	if out.Bounds() != sub.Bounds() {
+		t.Fatalf("bounds = %v, want %v", out.Bounds(), sub.Bounds())
+	}
+	for y := 3; y < 6; y++ {
+		for x := 2; x < 7; x++ {
+			if got := color.GrayModel.Convert(out.At(x, y)).(color.Gray).Y; got != 90 {
+				t.Fatalf("flat at (%d, %d) = %d, want 90", x, y, got)
+			}
+		}
+	}
+}
+
+func TestBayer(t *testing.T) {
+	want := []int{
+		0, 8, 2, 10,
+		12, 4, 14, 6,
+		3, 11, 1, 9,
+		15, 7, 13, 5,
+	}
+	for i, v := range bayer(4) {
+		if got := int((v + 0.5) * 16); got != want[i] {
+			t.Errorf("bayer(4)[%d] = %v (rank %d), want rank %d", i, v, got, want[i])
+		}
+	}
+}
+
+func TestRenderBayer(t *testing.T) {
+	// Mid gray, between the two glyphs, becomes a checkerboard of both.
+	flat := image.NewGray(image.Rect(0, 0, 4, 2))
+	for i := range flat.Pix {
+		flat.Pix[i] = 128
+	}
+	rows, err := Render(flat, Options{Width: 4, Height: 2, Charset: "@ ", Bayer: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"@ @ ", " @ @"}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %q, want %q", rows, want)
+	}
+}
+
+func TestSharpen(t *testing.T) {
+	// On a one-row grid the clamped kernel blurs with weights 1,2,1
+	// horizontally, so the step's sides are pushed apart.
+	lums := []float64{100, 100, 200, 200}
+	sharpen(lums, 4, 1, 1)
+	if want := []float64{100, 75, 225, 200}; !reflect.DeepEqual(lums, want) {
+		t.Errorf("got %v, want %v", lums, want)
+	}
+
+	lums = []float64{0, 0, 255, 255}
+	sharpen(lums, 2, 2, 2)
+	if want := []float64{0, 0, 255, 255}; !reflect.DeepEqual(lums, want) {
+		t.Errorf("clamped: got %v, want %v", lums, want)
+	}
+
+	lums = []float64{10, 200, 30, 90}
+	sharpen(lums, 2, 2, 0)
+	if want := []float64{10, 200, 30, 90}; !reflect.DeepEqual(lums, want) {
+		t.Errorf("amount 0: got %v, want %v", lums, want)
+	}
+}
+
+func TestStretchContrast(t *testing.T) {
+	lums := []float64{100, 110, 120, 130, 140}
+	stretchContrast(lums, 0)
+	if want := []float64{0, 63.75, 127.5, 191.25, 255}; !reflect.DeepEqual(lums, want) {
+		t.Errorf("got %v, want %v", lums, want)
+	}
+
+	// Clipping 20% of 5 cells ignores one outlier at each end.
+	lums = []float64{0, 100, 110, 120, 255}
+	stretchContrast(lums, 20)
+	if want := []float64{0, 0, 127.5, 255, 255}; !reflect.DeepEqual(lums, want) {
+		t.Errorf("clipped: got %v, want %v", lums, want)
+	}
+
+	flat := []float64{90, 90, 90}
+	stretchContrast(flat, 0)
+	if want := []float64{90, 90, 90}; !reflect.DeepEqual(flat, want) {
+		t.Errorf("flat: got %v, want %v", flat, want)
+	}
+}
+
+func TestRenderAutoContrast(t *testing.T) {
+	// A dim gradient from 100 to 140 reaches both ends of the ramp.
+	img := image.NewGray(image.Rect(0, 0, 5, 1))
+	for x := 0; x < 5; x++ {
+		img.SetGray(x, 0, color.Gray{uint8(100 + 10*x)})
+	}
+	rows, err := Render(img, Options{Width: 5, Height: 1, AutoContrast: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "@#=: "; rows[0] != want {
+		t.Errorf("got %q, want %q", rows[0], want)
+	}
+}
+
+func TestEqualizeHistogram(t *testing.T) {
+	// Three tones crowded at the dark end spread across the whole range.
+	lums := []float64{10, 10, 20, 20, 30, 30}
+	equalizeHistogram(lums)
+	if want := []float64{0, 0, 128, 128, 255, 255}; !reflect.DeepEqual(lums, want) {
+		t.Errorf("got %v, want %v", lums, want)
+	}
+
+	flat := []float64{90, 90, 90}
+	equalizeHistogram(flat)
+	if want := []float64{90, 90, 90}; !reflect.DeepEqual(flat, want) {
+		t.Errorf("flat: got %v, want %v", flat, want)
+	}
+}
+
+func TestRenderPixels(t *testing.T) {
+	// A 3x2 sprite with a transparent corner: one cell per pixel, whatever
+	// the requested size.
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	red, blue := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}
+	img.SetNRGBA(0, 0, red)
+	img.SetNRGBA(1, 0, blue)
+	img.SetNRGBA(2, 0, red)
+	img.SetNRGBA(0, 1, blue)
+	img.SetNRGBA(1, 1, red)
+	g, err := RenderPixels(img, Options{Width: 80, CharAspect: 0.3, TransparentSpace: true, AlphaThreshold: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"███", "██ "}; !reflect.DeepEqual(g.Text(), want) {
+		t.Errorf("got %q, want %q", g.Text(), want)
+	}
+	if got, want := g[0][1].FG, (color.RGBA{0, 0, 255, 255}); got != want {
+		t.Errorf("pixel 1,0 color = %v, want %v", got, want)
+	}
+}
+
+func TestRenderEdges(t *testing.T) {
+	// A bright square on black: its sides become '|' and '-', the flat
+	// interior and background stay blank.
+	img := image.NewGray(image.Rect(0, 0, 9, 9))
+	for y := 3; y < 6; y++ {
+		for x := 3; x < 6; x++ {
+			img.SetGray(x, y, color.Gray{255})
+		}
+	}
+	g, err := RenderEdges(img, Options{Width: 9, Height: 9, EdgeThreshold: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := g.Text()
+	if rows[0] != "         " {
+		t.Errorf("background row = %q, want blank", rows[0])
+	}
+	if got := rows[4][2]; got != '|' {
+		t.Errorf("left side = %q, want '|'", got)
+	}
+	if got := rows[2][4]; got != '-' {
+		t.Errorf("top side = %q, want '-'", got)
+	}
+	if got := rows[4][4]; got != ' ' {
+		t.Errorf("interior = %q, want ' '", got)
+	}
+}
+
+func TestEdgeGlyph(t *testing.T) {
+	for _, tc := range []struct {
+		gx, gy float64
+		want   rune
+	}{
+		{1, 0, '|'},
+		{-1, 0, '|'},
+		{0, 1, '-'},
+		{1, 1, '/'},
+		{-1, -1, '/'},
+		{1, -1, '\\'},
+	} {
+		if got := edgeGlyph(tc.gx, tc.gy); got != tc.want {
+			t.Errorf("edgeGlyph(%g, %g) = %q, want %q", tc.gx, tc.gy, got, tc.want)
+		}
+	}
+}
+
+func TestRenderOptionErrors(t *testing.T) {
+	img := gradient(10, 10)
+	for _, opts := range []Options{
+		{},
+		{Width: -1},
+		{Height: -1},
+		{Width: 10, Charset: "x"},
+		{Width: 10, Gamma: -1},
+		{Width: 10, Jobs: -1},
+		{Width: 10, AutoContrastClip: 60},
+		{Width: 10, BW: true, BWChars: "abc"},
+		{Width: 10, Contrast: -1},
+		{Width: 10, Brightness: 256},
+		{Width: 10, Levels: 1},
+		{Width: 10, Sharpen: -1},
+		{Width: 10, Blur: -1},
+		{Width: 10, Bayer: 3},
+		{Width: 10, Bayer: 4, Dither: true},
+	} {
+		if _, err := Render(img, opts); err == nil {
+			t.Errorf("Render(%+v) succeeded, want error", opts)
+		}
+	}
+}
+
+func TestRenderEmptyImage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 0, 10))
+	if _, err := Render(img, Options{Width: 10}); err == nil {
+		t.Error("Render of an empty image succeeded, want error")
+	}
+	if _, err := RenderHalfBlock(img, Options{Width: 10}); err == nil {
+		t.Error("RenderHalfBlock of an empty image succeeded, want error")
+	}
+}
+
+func TestRenderersRejectEmptyGrid(t *testing.T) {
+	img := gradient(10, 10)
+	opts, _ := Options{Width: 1}.withDefaults()
+	for _, size := range [][2]int{{0, 5}, {5, 0}, {-1, 5}} {
+		if _, err := renderASCII(img, size[0], size[1], opts); err == nil {
+			t.Errorf("renderASCII %dx%d succeeded, want error", size[0], size[1])
+		}
+		if _, err := renderBraille(img, size[0], size[1], opts); err == nil {
+			t.Errorf("renderBraille %dx%d succeeded, want error", size[0], size[1])
+		}
+		if _, err := renderHalfBlock(img, size[0], size[1], 1); err == nil {
+			t.Errorf("renderHalfBlock %dx%d succeeded, want error", size[0], size[1])
+		}
+	}
+}
+
+func TestLuminance8(t *testing.T) {
+	for _, tc := range []struct {
+		r, g, b uint32
+		want    uint8
+	}{
+		{0, 0, 0, 0},
+		{0xffff, 0xffff, 0xffff, 255},
+		{0xffff, 0, 0, 54},
+		{0, 0xffff, 0, 182},
+		{0, 0, 0xffff, 18},
+		// Grays between two 8-bit levels round to the nearer one.
+		{0x00ff, 0x00ff, 0x00ff, 1},
+		{0x01ff, 0x01ff, 0x01ff, 2},
+		{0x7fff, 0x7fff, 0x7fff, 127},
+		{0x40ff, 0x40ff, 0x40ff, 65},
+		{0xff00, 0xff00, 0xff00, 254},
+		{0xfffe, 0xfffe, 0xfffe, 255},
+	} {
+		if got := Luminance8(tc.r, tc.g, tc.b); got != tc.want {
+			t.Errorf("Luminance8(%#x, %#x, %#x) = %d, want %d", tc.r, tc.g, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestRGBAAtMatchesAt(t *testing.T) {
+	// Every channel value against every alpha, so NRGBA's premultiplication
+	// is checked exhaustively.
+	r := image.Rect(0, 0, 256, 256)
+	rgba, nrgba, gray := image.NewRGBA(r), image.NewNRGBA(r), image.NewGray(r)
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			i := rgba.PixOffset(x, y)
+			copy(rgba.Pix[i:], []uint8{uint8(min(x, y)), uint8(y / 2), 0, uint8(y)})
+			copy(nrgba.Pix[i:], []uint8{uint8(x), uint8(255 - x), uint8(x / 3), uint8(y)})
+			gray.Pix[gray.PixOffset(x, y)] = uint8(x ^ y)
+		}
+	}
+	for _, img := range []image.Image{rgba, nrgba, gray} {
+		for y := 0; y < 256; y++ {
+			for x := 0; x < 256; x++ {
+				r, g, b, a := rgbaAt(img, x, y)
+				wr, wg, wb, wa := img.At(x, y).RGBA()
+				if r != wr || g != wg || b != wb || a != wa {
+					t.Fatalf("%T at %d,%d: got %x %x %x %x, want %x %x %x %x", img, x, y, r, g, b, a, wr, wg, wb, wa)
+				}
+			}
+		}
+	}
+}
+
+func TestLumTableMatchesFloat(t *testing.T) {
+	// Over every 8-bit gray and a spread of colors, under the default and
+	// skewed weights, the table agrees with the float formula except where
+	// the two round a value within a hair of .5 differently.
+	for _, w := range [][3]float64{Rec709, {0.5, 0.3, 0.2}, {1, 0, 0}} {
+		table := newLumTable(w)
+		diffs := 0
+		for r := 0; r < 256; r += 3 {
+			for g := 0; g < 256; g += 5 {
+				for b := 0; b < 256; b += 7 {
+					r16, g16, b16 := uint32(r)*0x101, uint32(g)*0x101, uint32(b)*0x101
+					got, want := table.luminance(r16, g16, b16), weightedLuminance8(r16, g16, b16, w)
+					if d := int(got) - int(want); d < -1 || d > 1 {
+						t.Fatalf("weights %v, rgb(%d, %d, %d): table %d, float %d", w, r, g, b, got, want)
+					} else if d != 0 {
+						diffs++
+					}
+				}
+			}
+		}
+		if diffs > 0 {
+			t.Logf("weights %v: %d values off by one", w, diffs)
+		}
+	}
+}
+
+// benchImage is a synthetic 4000x3000 photo-sized image with smoothly varying
+// color, built once for the benchmarks.
+var benchImage = func() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4000, 3000))
+	for y := 0; y < 3000; y++ {
+		for x := 0; x < 4000; x++ {
+			i := img.PixOffset(x, y)
+			img.Pix[i+0] = uint8(x * 255 / 3999)
+			img.Pix[i+1] = uint8(y * 255 / 2999)
+			img.Pix[i+2] = uint8((x + y) % 256)
+			img.Pix[i+3] = 255
+		}
+	}
+	return img
+}()
+
+func BenchmarkRender(b *testing.B) {
+	for _, tc := range []struct {
+		name   string
+		sample SampleMode
+	}{
+		{"nearest", SampleNearest},
+		// Averaging measures the luminance of every source pixel.
+		{"average", SampleAverage},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			opts := Options{Width: 300, Sample: tc.sample}
+			for i := 0; i < b.N; i++ {
+				if _, err := Render(benchImage, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRenderImageTypes(b *testing.B) {
+	// Averaging reads every source pixel, through a fast path for the first
+	// three types and through At for the rest.
+	bounds := benchImage.Bounds()
+	nrgba := image.NewNRGBA(bounds)
+	gray := image.NewGray(bounds)
+	ycc := image.NewYCbCr(bounds, image.YCbCrSubsampleRatio420)
+	draw.Draw(nrgba, bounds, benchImage, image.Point{}, draw.Src)
+	draw.Draw(gray, bounds, benchImage, image.Point{}, draw.Src)
+	for _, img := range []image.Image{benchImage, nrgba, gray, ycc} {
+		b.Run(fmt.Sprintf("%T", img)[len("*image."):], func(b *testing.B) {
+			opts := Options{Width: 300, Sample: SampleAverage}
+			for i := 0; i < b.N; i++ {
+				if _, err := Render(img, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// gaussianKernel returns the normalized weights of a 1D Gaussian with
+// standard deviation sigma, reaching three deviations either side of the
+// center.
+func gaussianKernel(sigma float64) []float64 {
+	r := int(math.Ceil(3 * sigma))
+	k := make([]float64, 2*r+1)
+	sum := 0.0
+	for i := range k {
+		d := float64(i - r)
+		k[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += k[i]
+	}
+	for i := range k {
+		k[i] /= sum
+	}
+	return k
+}
+
+// blurImage returns img smoothed by a Gaussian of standard deviation sigma
+// pixels, run as a horizontal pass and then a vertical one over the
+// premultiplied channels, which blurs luminance alike. Pixels past the
+// borders repeat the edge. A sigma of 0 returns img itself. Rows are
+// blurred by up to jobs goroutines.
+func blurImage(img image.Image, sigma float64, jobs int) image.Image {
+	b := img.Bounds()
+	if sigma <= 0 || b.Empty() {
+		return img
+	}
+	w, h := b.Dx(), b.Dy()
+	k := gaussianKernel(sigma)
+	r := len(k) / 2
+	src := make([]float32, 4*w*h)
+	forEachRow(h, jobs, func(y int) {
+		for x := 0; x < w; x++ {
+			cr, cg, cb, ca := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			i := 4 * (y*w + x)
+			src[i], src[i+1], src[i+2], src[i+3] = float32(cr), float32(cg), float32(cb), float32(ca)
+		}
+	})
+	tmp := make([]float32, len(src))
+	forEachRow(h, jobs, func(y int) {
+		for x := 0; x < w; x++ {
+			var acc [4]float64
+			for j, kv := range k {
+				i := 4 * (y*w + min(max(x+j-r, 0), w-1))
+				for c := range acc {
+					acc[c] += kv * float64(src[i+c])
+				}
+			}
+			for c, v := range acc {
+				tmp[4*(y*w+x)+c] = float32(v)
+			}
+		}
+	})
+	dst := image.NewRGBA64(b)
+	forEachRow(h, jobs, func(y int) {
+		for x := 0; x < w; x++ {
+			var acc [4]float64
+			for j, kv := range k {
+				i := 4 * (min(max(y+j-r, 0), h-1)*w + x)
+				for c := range acc {
+					acc[c] += kv * float64(tmp[i+c])
+				}
+			}
+			ch := func(v float64) uint16 { return uint16(math.Max(0, math.Min(0xffff, v)) + 0.5) }
+			a := ch(acc[3])
+			dst.SetRGBA64(b.Min.X+x, b.Min.Y+y, color.RGBA64{min(ch(acc[0]), a), min(ch(acc[1]), a), min(ch(acc[2]), a), a})
+		}
+	})
+	return dst
+}
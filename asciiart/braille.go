@@ -0,0 +1,42 @@
+package asciiart
+
+import "image"
+
+// brailleBase is U+2800, the empty Braille pattern; dot bits are added to it.
+const brailleBase = 0x2800
+
+// brailleDots holds the dot bit for each subpixel of a 2-wide, 4-tall Braille
+// cell, indexed by [row][column].
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// renderBraille maps img onto a newW x newH grid of Braille characters. Each
+// character covers a 2x4 block of subpixels, and a dot is set for every
+// subpixel darker than opts.Threshold (brighter, with opts.Invert).
+func renderBraille(img image.Image, newW, newH int, opts Options) (Grid, error) {
+	if err := checkGrid(img, newW, newH); err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	gridW, gridH := newW*2, newH*4
+	g := newGrid(newW, newH)
+	forEachRow(newH, opts.Jobs, func(y int) {
+		for x := 0; x < newW; x++ {
+			var dots rune
+			for dy := 0; dy < 4; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					lum := sampleLuminance(img, 2*x+dx, 4*y+dy, gridW, gridH, opts)
+					if (lum < opts.Threshold) != opts.Invert {
+						dots |= brailleDots[dy][dx]
+					}
+				}
+			}
+			g[y][x] = Cell{Ch: brailleBase + dots, FG: pixelColor(img, samplePoint(x, y, newW, newH, bounds))}
+		}
+	})
+	return g, nil
+}
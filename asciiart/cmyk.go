@@ -0,0 +1,34 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+)
+
+// toRGB returns img converted to RGBA when it holds CMYK ink amounts, as
+// JPEGs from print workflows decode. Ink is subtractive: each channel takes
+// light away, so the colors and luminance come from CMYKToRGB rather than
+// from treating the channels as light. Converting once up front also spares
+// every later sample the per-pixel conversion. Other images are returned as
+// they are.
+func toRGB(img image.Image) image.Image {
+	src, ok := img.(*image.CMYK)
+	if !ok {
+		return img
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		si := src.PixOffset(b.Min.X, y)
+		di := dst.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x++ {
+			s := src.Pix[si : si+4 : si+4]
+			r, g, bl := color.CMYKToRGB(s[0], s[1], s[2], s[3])
+			d := dst.Pix[di : di+4 : di+4]
+			d[0], d[1], d[2], d[3] = r, g, bl, 0xff
+			si += 4
+			di += 4
+		}
+	}
+	return dst
+}
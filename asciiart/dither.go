@@ -0,0 +1,68 @@
+package asciiart
+
+import "math"
+
+// ditherFloydSteinberg quantizes the w x h luminance grid lums (0..255) in
+// place to the given number of evenly spaced levels, diffusing each cell's
+// quantization error onto its unvisited neighbors. Error that would fall
+// outside the grid is dropped.
+func ditherFloydSteinberg(lums []float64, w, h, levels int) {
+	step := 255.0 / float64(levels-1)
+	spread := func(x, y int, e float64) {
+		if x >= 0 && x < w && y < h {
+			lums[y*w+x] += e
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			old := math.Max(0, math.Min(255, lums[i]))
+			q := math.Round(old/step) * step
+			lums[i] = q
+			e := old - q
+			spread(x+1, y, e*7/16)
+			spread(x-1, y+1, e*3/16)
+			spread(x, y+1, e*5/16)
+			spread(x+1, y+1, e*1/16)
+		}
+	}
+}
+
+// bayerMatrices holds the Bayer threshold matrices of each size Options.Bayer
+// allows, row by row, as offsets in (-0.5, 0.5) of a quantization step.
+var bayerMatrices = map[int][]float64{2: bayer(2), 4: bayer(4), 8: bayer(8)}
+
+// bayer returns the n x n Bayer matrix, for n a power of two, built by
+// repeatedly tiling the previous one as 4M, 4M+2 / 4M+3, 4M+1, then centered
+// and scaled to offsets in (-0.5, 0.5).
+func bayer(n int) []float64 {
+	m := []int{0}
+	for size := 1; size < n; size *= 2 {
+		next := make([]int, 4*size*size)
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				v := 4 * m[y*size+x]
+				next[y*2*size+x] = v
+				next[y*2*size+x+size] = v + 2
+				next[(y+size)*2*size+x] = v + 3
+				next[(y+size)*2*size+x+size] = v + 1
+			}
+		}
+		m = next
+	}
+	out := make([]float64, len(m))
+	for i, v := range m {
+		out[i] = (float64(v)+0.5)/float64(len(m)) - 0.5
+	}
+	return out
+}
+
+// orderedDither returns lum, 0..255, nudged by the Bayer threshold of cell
+// (x, y) for n x n matrix by up to half of one step between levels, so a
+// flat area between two levels turns into a regular mix of both. Each cell
+// depends only on its own position, so rows can be dithered independently.
+func orderedDither(lum float64, x, y, n, levels int) float64 {
+	step := 255.0 / float64(levels-1)
+	lum += bayerMatrices[n][(y%n)*n+x%n] * step
+	return math.Max(0, math.Min(255, lum))
+}
@@ -0,0 +1,72 @@
+package asciiart
+
+import (
+	"image"
+	"math"
+)
+
+// sobelX and sobelY are the Sobel kernels for the horizontal and vertical
+// luminance gradients, indexed by [dy+1][dx+1].
+var (
+	sobelX = [3][3]float64{{-1, 0, 1}, {-2, 0, 2}, {-1, 0, 1}}
+	sobelY = [3][3]float64{{-1, -2, -1}, {0, 0, 0}, {1, 2, 1}}
+)
+
+// renderEdges maps img onto a newW x newH grid of outline glyphs. Sobel
+// gradients are taken over the sampled luminance grid, clamping the kernel at
+// the borders. Cells whose gradient magnitude exceeds opts.EdgeThreshold get
+// the glyph running along the edge, which is perpendicular to the gradient;
+// the rest are spaces.
+func renderEdges(img image.Image, newW, newH int, opts Options) (Grid, error) {
+	if err := checkGrid(img, newW, newH); err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	lums := make([]float64, newW*newH)
+	forEachRow(newH, opts.Jobs, func(y int) {
+		for x := 0; x < newW; x++ {
+			lums[y*newW+x] = float64(sampleLuminance(img, x, y, newW, newH, opts))
+		}
+	})
+	at := func(x, y int) float64 {
+		x = min(max(x, 0), newW-1)
+		y = min(max(y, 0), newH-1)
+		return lums[y*newW+x]
+	}
+
+	g := newGrid(newW, newH)
+	forEachRow(newH, opts.Jobs, func(y int) {
+		for x := 0; x < newW; x++ {
+			var gx, gy float64
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					v := at(x+dx, y+dy)
+					gx += sobelX[dy+1][dx+1] * v
+					gy += sobelY[dy+1][dx+1] * v
+				}
+			}
+			ch := ' '
+			if math.Hypot(gx, gy) > opts.EdgeThreshold {
+				ch = edgeGlyph(gx, gy)
+			}
+			g[y][x] = Cell{Ch: ch, FG: pixelColor(img, samplePoint(x, y, newW, newH, bounds))}
+		}
+	})
+	return g, nil
+}
+
+// edgeGlyph returns the character drawn along an edge whose luminance
+// gradient is (gx, gy), with y growing downward.
+func edgeGlyph(gx, gy float64) rune {
+	a := math.Mod(math.Atan2(gy, gx)*180/math.Pi+180, 180)
+	switch {
+	case a < 22.5 || a >= 157.5:
+		return '|' // gradient across, so the edge runs down
+	case a < 67.5:
+		return '/'
+	case a < 112.5:
+		return '-'
+	default:
+		return '\\'
+	}
+}
@@ -0,0 +1,119 @@
+package asciiart
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden from the current output")
+
+// fixtures are the sample images in testdata, each a kind of picture the
+// renderer should keep drawing the same way.
+var fixtures = []string{
+	"photo.jpg",      // smooth gradients and noise
+	"screenshot.png", // flat fills and hard edges
+	"lineart.png",    // thin strokes on white
+	"logo.png",       // antialiased shapes on a transparent background
+}
+
+// goldenModes are the options each fixture is rendered with.
+var goldenModes = []struct {
+	name string
+	opts Options
+}{
+	{"nearest", Options{Width: 60}},
+	{"average", Options{Width: 60, Sample: SampleAverage}},
+}
+
+// loadFixture decodes testdata/name.
+func loadFixture(tb testing.TB, name string) image.Image {
+	tb.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		tb.Fatalf("%s: %v", name, err)
+	}
+	return img
+}
+
+// TestGolden renders every fixture in every mode and compares the rows to
+// testdata/golden. After a deliberate change to the output, run
+//
+//	go test ./asciiart -run TestGolden -update
+//
+// and review the diff of the golden files.
+func TestGolden(t *testing.T) {
+	for _, name := range fixtures {
+		img := loadFixture(t, name)
+		for _, m := range goldenModes {
+			golden := filepath.Join("testdata", "golden", strings.TrimSuffix(name, filepath.Ext(name))+"-"+m.name+".txt")
+			rows, err := Render(img, m.opts)
+			if err != nil {
+				t.Fatalf("%s, %s: %v", name, m.name, err)
+			}
+			got := strings.Join(rows, "\n") + "\n"
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("%s, %s: output differs from %s:\n%s", name, m.name, golden, lineDiff(string(want), got))
+			}
+		}
+	}
+}
+
+// lineDiff lists the lines of want and got that differ, numbered from 1.
+func lineDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < max(len(w), len(g)); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			fmt.Fprintf(&b, "line %d:\n  want %q\n  got  %q\n", i+1, wl, gl)
+		}
+	}
+	return b.String()
+}
+
+// BenchmarkFixtures times rendering each fixture in each golden mode, so
+// speed can be compared on the same images the golden files check.
+func BenchmarkFixtures(b *testing.B) {
+	for _, name := range fixtures {
+		img := loadFixture(b, name)
+		for _, m := range goldenModes {
+			b.Run(strings.TrimSuffix(name, filepath.Ext(name))+"/"+m.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if _, err := Render(img, m.opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
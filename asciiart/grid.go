@@ -0,0 +1,35 @@
+package asciiart
+
+import "image/color"
+
+// Cell is one output character and the color it was sampled from.
+type Cell struct {
+	Ch    rune
+	FG    color.RGBA
+	BG    color.RGBA
+	HasBG bool // BG is meaningful (half-block cells)
+}
+
+// Grid is a rendered image, indexed by [row][column].
+type Grid [][]Cell
+
+func newGrid(w, h int) Grid {
+	g := make(Grid, h)
+	for y := range g {
+		g[y] = make([]Cell, w)
+	}
+	return g
+}
+
+// Text returns the glyphs of each row, without color.
+func (g Grid) Text() []string {
+	rows := make([]string, len(g))
+	for y, row := range g {
+		rs := make([]rune, len(row))
+		for x, c := range row {
+			rs[x] = c.Ch
+		}
+		rows[y] = string(rs)
+	}
+	return rows
+}
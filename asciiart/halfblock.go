@@ -0,0 +1,29 @@
+package asciiart
+
+import "image"
+
+// upperHalfBlock is drawn in the top pixel's color over a background in the
+// bottom pixel's color, so each character shows two pixel rows.
+const upperHalfBlock = '▀'
+
+// renderHalfBlock maps img onto a newW x newH grid of half-block characters
+// covering newW x 2*newH pixels. The result only reads correctly when encoded
+// with color. Rows are rendered by up to jobs goroutines.
+func renderHalfBlock(img image.Image, newW, newH, jobs int) (Grid, error) {
+	if err := checkGrid(img, newW, newH); err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	g := newGrid(newW, newH)
+	forEachRow(newH, jobs, func(y int) {
+		for x := 0; x < newW; x++ {
+			g[y][x] = Cell{
+				Ch:    upperHalfBlock,
+				FG:    pixelColor(img, samplePoint(x, 2*y, newW, 2*newH, bounds)),
+				BG:    pixelColor(img, samplePoint(x, 2*y+1, newW, 2*newH, bounds)),
+				HasBG: true,
+			}
+		}
+	})
+	return g, nil
+}
@@ -0,0 +1,161 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+)
+
+// DefaultWidth is the output width, in characters, that NewOptions uses
+// when neither WithWidth nor WithHeight sets a size.
+const DefaultWidth = 80
+
+// An Option sets part of the Options a render uses, so a call only names the
+// settings it changes. There is one for each exported field of Options:
+//
+//	rows, err := asciiart.RenderWith(img, asciiart.WithWidth(100), asciiart.WithGamma(2.2))
+//
+// Options apply in order, and a later one overrides an earlier one that sets
+// the same thing. Settings that exclude each other resolve the same way:
+// WithDither and WithBayer each turn the other off, as do WithGray and
+// WithLumWeights. WithWidth and WithHeight do not exclude each other: given
+// both, the grid is exactly that size and the image is stretched to fill it,
+// so pass only one to keep its aspect ratio.
+type Option func(*Options)
+
+// NewOptions returns the Options that opts produce, starting from the zero
+// value's defaults and a width of DefaultWidth. The result can be passed to
+// any renderer, such as RenderBraille, that takes Options.
+func NewOptions(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Width == 0 && o.Height == 0 {
+		o.Width = DefaultWidth
+	}
+	return o
+}
+
+// RenderWith is Render with the Options that opts produce.
+func RenderWith(img image.Image, opts ...Option) ([]string, error) {
+	return Render(img, NewOptions(opts...))
+}
+
+// WithWidth sets the output width in characters.
+func WithWidth(n int) Option { return func(o *Options) { o.Width = n } }
+
+// WithHeight sets the output height in rows.
+func WithHeight(n int) Option { return func(o *Options) { o.Height = n } }
+
+// WithCharAspect sets the character cell width / height ratio.
+func WithCharAspect(a float64) Option { return func(o *Options) { o.CharAspect = a } }
+
+// WithInvert reverses the ramp, for dark text on a light background.
+func WithInvert(invert bool) Option { return func(o *Options) { o.Invert = invert } }
+
+// WithCharset sets the glyph ramp, from dark to light.
+func WithCharset(s string) Option { return func(o *Options) { o.Charset = s } }
+
+// WithLevels limits the ramp to n glyphs spread evenly over it.
+func WithLevels(n int) Option { return func(o *Options) { o.Levels = n } }
+
+// WithBW switches Render from the ramp to the two BWChars glyphs.
+func WithBW(bw bool) Option { return func(o *Options) { o.BW = bw } }
+
+// WithBWChars sets the dark and then light glyph for WithBW.
+func WithBWChars(s string) Option { return func(o *Options) { o.BWChars = s } }
+
+// WithThreshold sets the luminance below which a pixel sets a Braille dot or
+// takes the dark BW glyph.
+func WithThreshold(t uint8) Option { return func(o *Options) { o.Threshold = t } }
+
+// WithGamma sets the luminance gamma; above 1 brightens midtones.
+func WithGamma(g float64) Option { return func(o *Options) { o.Gamma = g } }
+
+// WithTone sets a tone curve, applied after the gamma.
+func WithTone(pts ...TonePoint) Option { return func(o *Options) { o.Tone = pts } }
+
+// WithContrast sets the luminance multiplier around the 128 midpoint.
+func WithContrast(c float64) Option { return func(o *Options) { o.Contrast = c } }
+
+// WithBrightness sets the amount, -255..255, added to luminance.
+func WithBrightness(b float64) Option { return func(o *Options) { o.Brightness = b } }
+
+// WithAutoContrast stretches the sampled luminance range over the whole
+// ramp.
+func WithAutoContrast(on bool) Option { return func(o *Options) { o.AutoContrast = on } }
+
+// WithAutoContrastClip sets the percentage, 0..50, of darkest and brightest
+// cells that WithAutoContrast ignores.
+func WithAutoContrastClip(pct float64) Option {
+	return func(o *Options) { o.AutoContrastClip = pct }
+}
+
+// WithEqualize equalizes the sampled luminance histogram.
+func WithEqualize(on bool) Option { return func(o *Options) { o.Equalize = on } }
+
+// WithSharpen sets the strength of the unsharp mask applied to the sampled
+// luminance.
+func WithSharpen(amount float64) Option { return func(o *Options) { o.Sharpen = amount } }
+
+// WithBlur sets the standard deviation, in source pixels, of the Gaussian
+// blur applied before sampling.
+func WithBlur(sigma float64) Option { return func(o *Options) { o.Blur = sigma } }
+
+// WithSample sets how source pixels are downsampled.
+func WithSample(m SampleMode) Option { return func(o *Options) { o.Sample = m } }
+
+// WithSupersample averages an n x n lattice of pixels in each cell under
+// SampleNearest.
+func WithSupersample(n int) Option { return func(o *Options) { o.Supersample = n } }
+
+// WithDither turns Floyd-Steinberg dithering on or off, and ordered
+// dithering off.
+func WithDither(dither bool) Option {
+	return func(o *Options) { o.Dither, o.Bayer = dither, 0 }
+}
+
+// WithBayer turns on ordered dithering with a Bayer matrix of size 2, 4 or
+// 8, and Floyd-Steinberg dithering off; 0 turns it off.
+func WithBayer(size int) Option {
+	return func(o *Options) { o.Bayer, o.Dither = size, false }
+}
+
+// WithLumWeights sets the R, G, B luminance weights and the GrayWeighted
+// mode they need.
+func WithLumWeights(w [3]float64) Option {
+	return func(o *Options) { o.LumWeights, o.Gray = w, GrayWeighted }
+}
+
+// WithGray sets how luminance is read from the channels, dropping any
+// custom LumWeights.
+func WithGray(m GrayMode) Option {
+	return func(o *Options) { o.Gray, o.LumWeights = m, [3]float64{} }
+}
+
+// WithPerceptual measures luminance as CIE L* rather than weighting the
+// gamma-encoded channels.
+func WithPerceptual(perceptual bool) Option { return func(o *Options) { o.Perceptual = perceptual } }
+
+// WithBackground sets the color translucent pixels are composited over.
+func WithBackground(c color.RGBA) Option { return func(o *Options) { o.Background = c } }
+
+// WithTransparentSpace draws a space for cells whose alpha is below the
+// WithAlphaThreshold cutoff.
+func WithTransparentSpace(on bool) Option { return func(o *Options) { o.TransparentSpace = on } }
+
+// WithAlphaThreshold sets the alpha cutoff for WithTransparentSpace.
+func WithAlphaThreshold(a uint8) Option { return func(o *Options) { o.AlphaThreshold = a } }
+
+// WithFlipY emits Render's rows bottom to top.
+func WithFlipY(flip bool) Option { return func(o *Options) { o.FlipY = flip } }
+
+// WithEdgeThreshold sets the weakest gradient RenderEdges draws.
+func WithEdgeThreshold(t float64) Option { return func(o *Options) { o.EdgeThreshold = t } }
+
+// WithTimings has Render, RenderTo and RenderGrid add their sampling and
+// glyph times to t.
+func WithTimings(t *Timings) Option { return func(o *Options) { o.Timings = t } }
+
+// WithJobs sets how many rows are rendered concurrently.
+func WithJobs(n int) Option { return func(o *Options) { o.Jobs = n } }
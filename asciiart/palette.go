@@ -0,0 +1,60 @@
+package asciiart
+
+import "image"
+
+// paletteMask marks the transparent entries of a paletted image's palette:
+// those whose alpha is below the AlphaThreshold, such as the single
+// transparent index of a GIF. TransparentSpace then tests cells by index
+// rather than by averaged alpha, so a shape keeps a clean outline.
+type paletteMask struct {
+	img   *image.Paletted
+	clear [256]bool
+}
+
+// newPaletteMask returns the mask for img, or nil when img isn't paletted or
+// opts doesn't ask for TransparentSpace.
+func newPaletteMask(img image.Image, opts Options) *paletteMask {
+	p, ok := img.(*image.Paletted)
+	if !ok || !opts.TransparentSpace {
+		return nil
+	}
+	m := &paletteMask{img: p}
+	for i, c := range p.Palette {
+		if i == len(m.clear) {
+			break
+		}
+		_, _, _, a := c.RGBA()
+		m.clear[i] = uint8(a>>8) < opts.AlphaThreshold
+	}
+	return m
+}
+
+// cell returns the unadjusted luminance of cell (x, y) of a gridW x gridH
+// grid laid over the image, and whether the cell is transparent. Sampling a
+// single pixel, the cell is transparent when that pixel is. Averaging, it is
+// when transparent pixels outnumber the rest, and otherwise its luminance is
+// the mean of only the opaque pixels, so the background doesn't bleed into
+// the shape's edge.
+func (m *paletteMask) cell(x, y, gridW, gridH int, opts Options) (lum uint8, clear bool) {
+	bounds := m.img.Bounds()
+	if opts.Sample != SampleAverage {
+		p := samplePoint(x, y, gridW, gridH, bounds)
+		return luminanceAt(m.img, p.X, p.Y, opts), m.clear[m.img.ColorIndexAt(p.X, p.Y)]
+	}
+	r := cellRect(x, y, gridW, gridH, bounds.Dx(), bounds.Dy()).Add(bounds.Min)
+	var sum, n, cleared int
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			if m.clear[m.img.ColorIndexAt(px, py)] {
+				cleared++
+				continue
+			}
+			sum += int(luminanceAt(m.img, px, py, opts))
+			n++
+		}
+	}
+	if cleared > n {
+		return 0, true
+	}
+	return uint8((sum + n/2) / n), false
+}
@@ -0,0 +1,36 @@
+package asciiart
+
+import (
+	"runtime"
+	"sync"
+)
+
+// forEachRow calls fn for every row in [0, h), splitting the rows into
+// contiguous bands handled by up to jobs goroutines (runtime.NumCPU() when
+// jobs is 0). fn must only write state belonging to its own row.
+func forEachRow(h, jobs int, fn func(y int)) {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > h {
+		jobs = h
+	}
+	if jobs <= 1 {
+		for y := 0; y < h; y++ {
+			fn(y)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		y0, y1 := j*h/jobs, (j+1)*h/jobs
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := y0; y < y1; y++ {
+				fn(y)
+			}
+		}()
+	}
+	wg.Wait()
+}
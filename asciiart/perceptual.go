@@ -0,0 +1,54 @@
+package asciiart
+
+import "math"
+
+// lstarSteps is how finely perceptualTable divides linear luminance, 0..1,
+// on its way to L*. Near black, where L* is steepest, one step is still
+// under a unit of the 0..255 output.
+const lstarSteps = 4096
+
+// perceptualTable converts 8-bit sRGB channels to CIE L*, scaled to 0..255:
+// each channel is linearized and weighted, the sum is the relative
+// luminance Y, and L* follows from Y's cube root. Both curves are looked up
+// rather than computed per pixel.
+type perceptualTable struct {
+	linear [3][256]float64       // weighted linear light of each channel value
+	lstar  [lstarSteps + 1]uint8 // L* of Y = i/lstarSteps, scaled to 0..255
+}
+
+// newPerceptualTable returns the table for channel weights w, which sum to 1.
+func newPerceptualTable(w [3]float64) *perceptualTable {
+	var t perceptualTable
+	for v := 0; v < 256; v++ {
+		l := srgbToLinear(float64(v) / 255)
+		for c := range t.linear {
+			t.linear[c][v] = w[c] * l
+		}
+	}
+	for i := range t.lstar {
+		t.lstar[i] = uint8(math.Round(lightness(float64(i)/lstarSteps) * 255 / 100))
+	}
+	return &t
+}
+
+// luminance returns the L*, 0..255, of the 16-bit channels r, g and b.
+func (t *perceptualTable) luminance(r, g, b uint32) uint8 {
+	y := t.linear[0][to8(r)] + t.linear[1][to8(g)] + t.linear[2][to8(b)]
+	return t.lstar[min(int(y*lstarSteps+0.5), lstarSteps)]
+}
+
+// srgbToLinear undoes the sRGB transfer curve on v, 0..1.
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// lightness returns the CIE L*, 0..100, of relative luminance y, 0..1.
+func lightness(y float64) float64 {
+	if y <= 216.0/24389 {
+		return y * 24389 / 27
+	}
+	return 116*math.Cbrt(y) - 16
+}
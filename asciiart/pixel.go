@@ -0,0 +1,36 @@
+package asciiart
+
+import "image"
+
+// fullBlock is drawn in a pixel's color to show that one pixel.
+const fullBlock = '█'
+
+// RenderPixels draws every pixel of img as one full block in its color,
+// without resampling, for pixel art such as sprites. Width, Height,
+// CharAspect and Sample are ignored, and like RenderHalfBlock the result
+// only reads correctly when encoded with color. With TransparentSpace,
+// pixels whose alpha is below AlphaThreshold are left blank.
+func RenderPixels(img image.Image, opts Options) (Grid, error) {
+	b := img.Bounds()
+	if err := checkGrid(img, b.Dx(), b.Dy()); err != nil {
+		return nil, err
+	}
+	opts.Width, opts.Height = b.Dx(), b.Dy()
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	g := newGrid(b.Dx(), b.Dy())
+	forEachRow(b.Dy(), opts.Jobs, func(y int) {
+		for x := range g[y] {
+			p := image.Pt(b.Min.X+x, b.Min.Y+y)
+			c := pixelColor(img, p)
+			if opts.TransparentSpace && c.A < opts.AlphaThreshold {
+				g[y][x] = Cell{Ch: ' ', FG: c}
+				continue
+			}
+			g[y][x] = Cell{Ch: fullBlock, FG: c}
+		}
+	})
+	return g, nil
+}
@@ -0,0 +1,420 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+)
+
+// renderASCII maps img onto a newW x newH grid of glyphs chosen from the
+// luminance ramp, each carrying the color of the pixel it was sampled from,
+// or under SampleAverage or Supersample the mean color of its cell.
+func renderASCII(img image.Image, newW, newH int, opts Options) (Grid, error) {
+	start := time.Now()
+	r, err := newASCIIRenderer(img, newW, newH, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.Timings.addSample(start)
+	start = time.Now()
+	g := newGrid(newW, newH)
+	forEachRow(newH, opts.Jobs, func(y int) {
+		if opts.FlipY {
+			r.row(y, g[newH-1-y])
+		} else {
+			r.row(y, g[y])
+		}
+	})
+	opts.Timings.addGlyphs(start)
+	return g, nil
+}
+
+// asciiRenderer holds the sampled luminance of an image, from which rows of
+// glyphs are picked one at a time.
+type asciiRenderer struct {
+	img        image.Image
+	newW, newH int
+	opts       Options
+	charset    []rune // dark to light, reversed for Invert
+	levels     int
+	lums       []float64    // newW x newH, 0..255
+	clear      []bool       // newW x newH, transparent cells of a paletted image; nil otherwise
+	colors     []color.RGBA // newW x newH, mean cell colors under SampleAverage or Supersample; nil otherwise
+}
+
+// newASCIIRenderer samples img onto a newW x newH luminance grid. The whole
+// grid is sampled up front so it can be stretched as a whole, and so
+// dithering can diffuse quantization error across neighboring cells, before
+// glyphs are picked.
+func newASCIIRenderer(img image.Image, newW, newH int, opts Options) (*asciiRenderer, error) {
+	if err := checkGrid(img, newW, newH); err != nil {
+		return nil, err
+	}
+	charset := []rune(opts.Charset)
+	if opts.BW {
+		charset = []rune(opts.BWChars)
+	}
+	if opts.Invert {
+		// reverse
+		for i, j := 0, len(charset)-1; i < j; i, j = i+1, j-1 {
+			charset[i], charset[j] = charset[j], charset[i]
+		}
+	}
+
+	levels := len(charset)
+	if opts.Levels > 0 && opts.Levels < levels {
+		levels = opts.Levels
+	}
+
+	lums := make([]float64, newW*newH)
+	var clear []bool
+	var colors []color.RGBA
+	if opts.Sample == SampleAverage || opts.Supersample > 1 {
+		colors = make([]color.RGBA, newW*newH)
+	}
+	bounds := img.Bounds()
+	// cell measures the luminance and mean color of cell (x, y) when colors
+	// is set.
+	cell := func(x, y int) (uint8, color.RGBA) {
+		if opts.Supersample > 1 {
+			return supersampleCell(img, x, y, newW, newH, opts.Supersample, opts)
+		}
+		return averageCell(img, cellRect(x, y, newW, newH, bounds.Dx(), bounds.Dy()).Add(bounds.Min), opts)
+	}
+	if mask := newPaletteMask(img, opts); mask != nil {
+		clear = make([]bool, newW*newH)
+		forEachRow(newH, opts.Jobs, func(y int) {
+			for x := 0; x < newW; x++ {
+				l, c := mask.cell(x, y, newW, newH, opts)
+				clear[y*newW+x] = c
+				if colors != nil {
+					var sl uint8
+					sl, colors[y*newW+x] = cell(x, y)
+					if opts.Supersample > 1 {
+						l = sl
+					}
+				}
+				lums[y*newW+x] = float64(adjustLuminance(l, opts))
+			}
+		})
+	} else if colors != nil {
+		// The glyph and its color come from one pass over the cell, so they
+		// agree.
+		forEachRow(newH, opts.Jobs, func(y int) {
+			for x := 0; x < newW; x++ {
+				l, c := cell(x, y)
+				lums[y*newW+x], colors[y*newW+x] = float64(adjustLuminance(l, opts)), c
+			}
+		})
+	} else {
+		forEachRow(newH, opts.Jobs, func(y int) {
+			for x := 0; x < newW; x++ {
+				lums[y*newW+x] = float64(sampleLuminance(img, x, y, newW, newH, opts)) // 0..255
+			}
+		})
+	}
+	if opts.Sharpen > 0 {
+		sharpen(lums, newW, newH, opts.Sharpen)
+	}
+	if opts.AutoContrast {
+		stretchContrast(lums, opts.AutoContrastClip)
+	}
+	if opts.Equalize {
+		equalizeHistogram(lums)
+	}
+	if opts.Dither {
+		ditherFloydSteinberg(lums, newW, newH, levels)
+	}
+	return &asciiRenderer{img: img, newW: newW, newH: newH, opts: opts, charset: charset, levels: levels, lums: lums, clear: clear, colors: colors}, nil
+}
+
+// row fills cells, newW long, with the glyphs of row y.
+func (r *asciiRenderer) row(y int, cells []Cell) {
+	bounds := r.img.Bounds()
+	for x := 0; x < r.newW; x++ {
+		var fg color.RGBA
+		if r.colors != nil {
+			fg = r.colors[y*r.newW+x]
+		} else {
+			fg = pixelColor(r.img, samplePoint(x, y, r.newW, r.newH, bounds))
+		}
+		if r.transparent(x, y) {
+			cells[x] = Cell{Ch: ' ', FG: fg}
+			continue
+		}
+		lum := r.lums[y*r.newW+x]
+		if r.opts.Bayer > 0 {
+			lum = orderedDither(lum, x, y, r.opts.Bayer, r.levels)
+		}
+		idx := 0
+		if r.opts.BW {
+			// 1-bit: split the two glyphs at Threshold, not the midpoint.
+			if lum >= float64(r.opts.Threshold) {
+				idx = 1
+			}
+		} else {
+			// Quantize to a band, then take the glyph at the same fraction
+			// of the ramp; with every level in use the two are the same.
+			band := math.Round(lum * float64(r.levels-1) / 255.0)
+			idx = int(math.Round(band * float64(len(r.charset)-1) / float64(r.levels-1)))
+		}
+		cells[x] = Cell{Ch: r.charset[idx], FG: fg}
+	}
+}
+
+// transparent reports whether TransparentSpace blanks cell (x, y).
+func (r *asciiRenderer) transparent(x, y int) bool {
+	if !r.opts.TransparentSpace {
+		return false
+	}
+	if r.clear != nil {
+		return r.clear[y*r.newW+x]
+	}
+	if r.opts.Supersample > 1 {
+		return r.colors[y*r.newW+x].A < r.opts.AlphaThreshold
+	}
+	return sampleAlpha(r.img, x, y, r.newW, r.newH, r.opts.Sample) < r.opts.AlphaThreshold
+}
+
+// pixelColor returns the 8-bit color of img at p.
+func pixelColor(img image.Image, p image.Point) color.RGBA {
+	r, g, b, a := rgbaAt(img, p.X, p.Y)
+	return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+}
+
+// samplePoint returns the source pixel that nearest-neighbor sampling uses
+// for cell (x, y) of a gridW x gridH grid laid over bounds: the one under the
+// cell's center, so the image isn't shifted toward its top-left corner.
+func samplePoint(x, y, gridW, gridH int, bounds image.Rectangle) image.Point {
+	sx := int((float64(x) + 0.5) * float64(bounds.Dx()) / float64(gridW))
+	if sx >= bounds.Dx() {
+		sx = bounds.Dx() - 1
+	}
+	sy := int((float64(y) + 0.5) * float64(bounds.Dy()) / float64(gridH))
+	if sy >= bounds.Dy() {
+		sy = bounds.Dy() - 1
+	}
+	return image.Pt(bounds.Min.X+sx, bounds.Min.Y+sy)
+}
+
+// sampleLuminance returns the adjusted luminance of cell (x, y) of a
+// gridW x gridH grid laid over img, using the sampling mode in opts.
+func sampleLuminance(img image.Image, x, y, gridW, gridH int, opts Options) uint8 {
+	bounds := img.Bounds()
+	var l uint8
+	if opts.Sample == SampleAverage {
+		cell := cellRect(x, y, gridW, gridH, bounds.Dx(), bounds.Dy()).Add(bounds.Min)
+		l = averageLuminance(img, cell, opts)
+	} else {
+		p := samplePoint(x, y, gridW, gridH, bounds)
+		l = luminanceAt(img, p.X, p.Y, opts)
+	}
+	return adjustLuminance(l, opts)
+}
+
+// sampleAlpha returns the alpha, 0..255, of cell (x, y) of a gridW x gridH
+// grid laid over img: that of the sampled pixel, or the mean over the cell's
+// rectangle when averaging.
+func sampleAlpha(img image.Image, x, y, gridW, gridH int, mode SampleMode) uint8 {
+	bounds := img.Bounds()
+	if mode == SampleAverage {
+		r := cellRect(x, y, gridW, gridH, bounds.Dx(), bounds.Dy()).Add(bounds.Min)
+		var sum, n int
+		for py := r.Min.Y; py < r.Max.Y; py++ {
+			for px := r.Min.X; px < r.Max.X; px++ {
+				_, _, _, a := rgbaAt(img, px, py)
+				sum += int(a >> 8)
+				n++
+			}
+		}
+		return uint8((sum + n/2) / n)
+	}
+	p := samplePoint(x, y, gridW, gridH, bounds)
+	_, _, _, a := rgbaAt(img, p.X, p.Y)
+	return uint8(a >> 8)
+}
+
+// adjustLuminance applies opts' gamma, then its tone curve, then contrast
+// around the 128 midpoint, then brightness to l, clamping the result to
+// 0..255.
+func adjustLuminance(l uint8, opts Options) uint8 {
+	if opts.Gamma == 1 && opts.tone == nil && opts.Contrast == 1 && opts.Brightness == 0 {
+		return l
+	}
+	v := 255 * math.Pow(float64(l)/255, 1/opts.Gamma)
+	if opts.tone != nil {
+		v = opts.tone[int(v+0.5)]
+	}
+	v = (v-128)*opts.Contrast + 128 + opts.Brightness
+	return uint8(math.Max(0, math.Min(255, v)) + 0.5)
+}
+
+// cellRect returns the source rectangle, relative to the image origin, covered
+// by output cell (x, y). When the image is smaller than the grid a cell may not
+// span a whole pixel; it is widened to one so it is never empty.
+func cellRect(x, y, newW, newH, origW, origH int) image.Rectangle {
+	x0, x1 := x*origW/newW, (x+1)*origW/newW
+	y0, y1 := y*origH/newH, (y+1)*origH/newH
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	return image.Rect(x0, y0, x1, y1)
+}
+
+// averageLuminance returns the mean luminance of the pixels in r, each
+// composited over opts.Background.
+func averageLuminance(img image.Image, r image.Rectangle, opts Options) uint8 {
+	var sum, n int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sum += int(luminanceAt(img, x, y, opts))
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return uint8((sum + n/2) / n)
+}
+
+// averageCell returns the mean luminance of the pixels in r, like
+// averageLuminance, along with their mean color, premultiplied as pixelColor
+// returns it.
+func averageCell(img image.Image, r image.Rectangle, opts Options) (uint8, color.RGBA) {
+	var sum, n int
+	var cr, cg, cb, ca uint64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			pr, pg, pb, pa := rgbaAt(img, x, y)
+			sum += int(compositeLuminance(pr, pg, pb, pa, opts))
+			cr, cg, cb, ca = cr+uint64(pr), cg+uint64(pg), cb+uint64(pb), ca+uint64(pa)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, color.RGBA{}
+	}
+	mean := func(v uint64) uint8 { return uint8((v + uint64(n)/2) / uint64(n) >> 8) }
+	return uint8((sum + n/2) / n), color.RGBA{mean(cr), mean(cg), mean(cb), mean(ca)}
+}
+
+// luminanceAt returns the luminance of img at (x, y) composited over
+// opts.Background, weighting channels by opts.LumWeights unless opts.Gray
+// reads them another way.
+func luminanceAt(img image.Image, x, y int, opts Options) uint8 {
+	r, g, b, a := rgbaAt(img, x, y)
+	return compositeLuminance(r, g, b, a, opts)
+}
+
+// compositeLuminance is luminanceAt for a pixel's 16-bit premultiplied
+// channels.
+func compositeLuminance(r, g, b, a uint32, opts Options) uint8 {
+	if opts.Gray == GrayAlpha {
+		return 255 - to8(a)
+	}
+	bg := opts.Background
+	// RGBA returns alpha-premultiplied channels, so compositing only adds the
+	// share of bg that the pixel doesn't cover.
+	t := 0xffff - a
+	r += uint32(bg.R) * 0x101 * t / 0xffff
+	g += uint32(bg.G) * 0x101 * t / 0xffff
+	b += uint32(bg.B) * 0x101 * t / 0xffff
+	if opts.Gray != GrayWeighted {
+		return grayLuminance(r, g, b, opts.Gray)
+	}
+	if opts.perceptual != nil {
+		return opts.perceptual.luminance(r, g, b)
+	}
+	return opts.lum.luminance(r, g, b)
+}
+
+// grayLuminance reads the 16-bit channels r, g and b as mode says, for input
+// that is already gray, where weighting them only lets a tint through.
+func grayLuminance(r, g, b uint32, mode GrayMode) uint8 {
+	switch mode {
+	case GrayRed:
+		return to8(r)
+	case GrayGreen:
+		return to8(g)
+	case GrayBlue:
+		return to8(b)
+	default:
+		return to8((r + g + b + 1) / 3)
+	}
+}
+
+// rgbaAt returns img.At(x, y).RGBA() for (x, y) inside img. The common
+// concrete types are read straight from Pix, skipping the interface call and
+// the color value At returns; other types go through At.
+func rgbaAt(img image.Image, x, y int) (r, g, b, a uint32) {
+	switch p := img.(type) {
+	case *image.RGBA:
+		s := p.Pix[p.PixOffset(x, y):]
+		return uint32(s[0]) * 0x101, uint32(s[1]) * 0x101, uint32(s[2]) * 0x101, uint32(s[3]) * 0x101
+	case *image.NRGBA:
+		// Premultiplied as color.NRGBA.RGBA does it.
+		s := p.Pix[p.PixOffset(x, y):]
+		a := uint32(s[3])
+		return uint32(s[0]) * 0x101 * a / 0xff, uint32(s[1]) * 0x101 * a / 0xff, uint32(s[2]) * 0x101 * a / 0xff, a * 0x101
+	case *image.Gray:
+		v := uint32(p.Pix[p.PixOffset(x, y)]) * 0x101
+		return v, v, v, 0xffff
+	}
+	return img.At(x, y).RGBA()
+}
+
+// lumTable holds, for every 8-bit channel value, that channel's weighted
+// contribution to luminance in 1/65536ths, so luminance takes three lookups
+// and a shift rather than float math.
+type lumTable [3][256]uint32
+
+// newLumTable returns the table for channel weights w, which sum to 1.
+func newLumTable(w [3]float64) *lumTable {
+	var t lumTable
+	for c := range t {
+		for v := range t[c] {
+			t[c][v] = uint32(math.Round(w[c] * float64(v) * 65536))
+		}
+	}
+	return &t
+}
+
+// luminance is weightedLuminance8 of the 16-bit channels r, g and b, which
+// it matches to within rounding.
+func (t *lumTable) luminance(r, g, b uint32) uint8 {
+	l := (t[0][to8(r)] + t[1][to8(g)] + t[2][to8(b)] + 1<<15) >> 16
+	return uint8(min(l, 255))
+}
+
+// to8 rounds a 16-bit channel to the nearest 8-bit value.
+func to8(v uint32) uint8 {
+	return uint8((v*255 + 0x7fff) / 0xffff)
+}
+
+// Luminance8 returns the Rec. 709 luma, 0..255, of an opaque color given as
+// 16-bit channels, such as those returned by color.Color.RGBA.
+func Luminance8(r, g, b uint32) uint8 {
+	return weightedLuminance8(r, g, b, Rec709)
+}
+
+// weightedLuminance8 is like Luminance8 but weights the channels by w, which
+// should sum to 1.
+func weightedLuminance8(r, g, b uint32, w [3]float64) uint8 {
+	// Convert 16-bit per channel to 8-bit and compute luma. Dividing by
+	// 257 maps 0xffff to 255 exactly and, unlike dropping the low byte,
+	// doesn't bias every pixel darker; the luma is rounded once, below.
+	r8 := float64(r) / 257
+	g8 := float64(g) / 257
+	b8 := float64(b) / 257
+	l := w[0]*r8 + w[1]*g8 + w[2]*b8
+	if l < 0 {
+		l = 0
+	} else if l > 255 {
+		l = 255
+	}
+	return uint8(l + 0.5)
+}
@@ -0,0 +1,30 @@
+package asciiart
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// lanczos3 is the three-lobed Lanczos kernel: sharper than a box filter, so
+// fine detail such as hair and small text survives downscaling.
+var lanczos3 = &draw.Kernel{Support: 3, At: func(t float64) float64 {
+	if t == 0 {
+		return 1
+	}
+	pt := math.Pi * t
+	return 3 * math.Sin(pt) * math.Sin(pt/3) / (pt * pt)
+}}
+
+// resample returns img scaled to w x h pixels when mode is SampleLanczos, so
+// that a w x h grid samples exactly one pixel per cell. Other modes sample
+// the source directly and get img back unchanged.
+func resample(img image.Image, w, h int, mode SampleMode) image.Image {
+	if mode != SampleLanczos || img.Bounds().Empty() || w <= 0 || h <= 0 {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	lanczos3.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,32 @@
+package asciiart
+
+import (
+	"image"
+	"image/color"
+)
+
+// supersampleCell returns the mean luminance and color, premultiplied as
+// pixelColor returns it, of an n x n lattice of points spread evenly over
+// cell (x, y) of a gridW x gridH grid laid over img. Each point takes the
+// pixel under it, clamped to the image, so for n = 1 this is the single
+// pixel samplePoint picks.
+func supersampleCell(img image.Image, x, y, gridW, gridH, n int, opts Options) (uint8, color.RGBA) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	var sum int
+	var cr, cg, cb, ca uint64
+	for j := 0; j < n; j++ {
+		fy := float64(y) + (float64(j)+0.5)/float64(n)
+		py := bounds.Min.Y + min(int(fy*float64(h)/float64(gridH)), h-1)
+		for i := 0; i < n; i++ {
+			fx := float64(x) + (float64(i)+0.5)/float64(n)
+			px := bounds.Min.X + min(int(fx*float64(w)/float64(gridW)), w-1)
+			pr, pg, pb, pa := rgbaAt(img, px, py)
+			sum += int(compositeLuminance(pr, pg, pb, pa, opts))
+			cr, cg, cb, ca = cr+uint64(pr), cg+uint64(pg), cb+uint64(pb), ca+uint64(pa)
+		}
+	}
+	k := n * n
+	mean := func(v uint64) uint8 { return uint8((v + uint64(k)/2) / uint64(k) >> 8) }
+	return uint8((sum + k/2) / k), color.RGBA{mean(cr), mean(cg), mean(cb), mean(ca)}
+}
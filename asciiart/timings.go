@@ -0,0 +1,25 @@
+package asciiart
+
+import "time"
+
+// Timings records how long the phases of Render, RenderTo and RenderGrid
+// took, when Options.Timings points to it. Durations are added to those
+// already there, so one Timings can total several images or frames.
+type Timings struct {
+	Sample time.Duration // blurring, resampling, and measuring each cell's luminance
+	Glyphs time.Duration // picking each cell's glyph and color; for RenderTo, also writing the rows
+}
+
+// addSample adds the time since start to t.Sample, if t is set.
+func (t *Timings) addSample(start time.Time) {
+	if t != nil {
+		t.Sample += time.Since(start)
+	}
+}
+
+// addGlyphs adds the time since start to t.Glyphs, if t is set.
+func (t *Timings) addGlyphs(start time.Time) {
+	if t != nil {
+		t.Glyphs += time.Since(start)
+	}
+}
@@ -0,0 +1,52 @@
+package asciiart
+
+import (
+	"errors"
+	"math"
+)
+
+// TonePoint is one control point of a tone curve: luminance In, 0..255,
+// maps to Out, which is clamped to 0..255.
+type TonePoint struct {
+	In, Out float64
+}
+
+// toneTable maps every 8-bit luminance through a tone curve.
+type toneTable [256]float64
+
+// newToneTable validates pts, whose In values must rise strictly within
+// 0..255, and returns the curve through them, interpolated linearly between
+// points and held flat beyond the first and last.
+func newToneTable(pts []TonePoint) (*toneTable, error) {
+	if len(pts) < 2 {
+		return nil, errors.New("asciiart: tone curve needs at least 2 points")
+	}
+	for i, p := range pts {
+		if p.In < 0 || p.In > 255 {
+			return nil, errors.New("asciiart: tone curve inputs must be between 0 and 255")
+		}
+		if i > 0 && p.In <= pts[i-1].In {
+			return nil, errors.New("asciiart: tone curve inputs must increase")
+		}
+	}
+	var t toneTable
+	j := 0
+	for v := range t {
+		x := float64(v)
+		for j < len(pts)-2 && x > pts[j+1].In {
+			j++
+		}
+		a, b := pts[j], pts[j+1]
+		var y float64
+		switch {
+		case x <= a.In:
+			y = a.Out
+		case x >= b.In:
+			y = b.Out
+		default:
+			y = a.Out + (b.Out-a.Out)*(x-a.In)/(b.In-a.In)
+		}
+		t[v] = math.Max(0, math.Min(255, y))
+	}
+	return &t, nil
+}
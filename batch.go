@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// formatExt returns the file extension for batch output in the given -format.
+func formatExt(format string) string {
+	switch format {
+	case "html":
+		return ".html"
+	case "svg":
+		return ".svg"
+	case "json":
+		return ".json"
+	case "png":
+		return ".png"
+	case "pgm":
+		return ".pgm"
+	default:
+		return ".txt"
+	}
+}
+
+// runBatch converts each of paths, which lie under root, with convert. The
+// result is written under the image's base name with ext, beside the image or,
+// when outDir is set, at the same relative location under outDir. A failed
+// image is reported to stderr and its partial output removed, and the run
+// carries on with the rest.
+func runBatch(root string, paths []string, outDir, ext string, convert func(out *bufio.Writer, path string) error) (ok, failed int) {
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if outDir != "" {
+			rel, err := filepath.Rel(absPath(root), absPath(dir))
+			if err != nil {
+				rel = "."
+			}
+			dir = filepath.Join(outDir, rel)
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s: %v\n", p, err)
+				failed++
+				continue
+			}
+		}
+		base := filepath.Base(p)
+		dst := filepath.Join(dir, strings.TrimSuffix(base, filepath.Ext(base))+ext)
+		if err := convertFile(dst, p, convert); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", p, err)
+			failed++
+			continue
+		}
+		ok++
+	}
+	return ok, failed
+}
+
+// convertFile runs convert for src into a new file at dst, removing dst again
+// if anything fails.
+func convertFile(dst, src string, convert func(out *bufio.Writer, path string) error) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(f)
+	err = convert(out, src)
+	if err == nil {
+		err = out.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
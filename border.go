@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// borderStyle holds the box-drawing characters for a frame.
+type borderStyle struct {
+	topLeft, horizontal, topRight, vertical, bottomLeft, bottomRight rune
+}
+
+// borderStyles are the -border-style presets.
+var borderStyles = map[string]borderStyle{
+	"single":  {'┌', '─', '┐', '│', '└', '┘'},
+	"double":  {'╔', '═', '╗', '║', '╚', '╝'},
+	"rounded": {'╭', '─', '╮', '│', '╰', '╯'},
+	"ascii":   {'+', '-', '+', '|', '+', '+'},
+}
+
+// addBorder frames rows with st. Rows are first padded with spaces to the
+// visible width of the widest, so ANSI escapes don't throw off the right edge.
+func addBorder(rows []string, st borderStyle) []string {
+	rows = padRows(rows)
+	width := 0
+	if len(rows) > 0 {
+		width = visibleWidth(rows[0])
+	}
+	rule := strings.Repeat(string(st.horizontal), width)
+	out := make([]string, 0, len(rows)+2)
+	out = append(out, string(st.topLeft)+rule+string(st.topRight))
+	for _, row := range rows {
+		out = append(out, string(st.vertical)+row+string(st.vertical))
+	}
+	return append(out, string(st.bottomLeft)+rule+string(st.bottomRight))
+}
+
+// padRows returns rows with spaces added to the right of each to match the
+// visible width of the widest, not counting ANSI escapes. Rows that are
+// already as wide are returned as they are.
+func padRows(rows []string) []string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, visibleWidth(row))
+	}
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		out[i] = row + strings.Repeat(" ", width-visibleWidth(row))
+	}
+	return out
+}
+
+// visibleWidth returns the number of runes in s, not counting ANSI CSI escape
+// sequences such as color codes.
+func visibleWidth(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '[' {
+			// Skip parameters up to and including the final byte.
+			i += 2
+			for i < len(s) && (s[i] < 0x40 || s[i] > 0x7e) {
+				i++
+			}
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"sort"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// calibrateSize is the size, in pixels per em, glyphs are drawn at to
+// measure their ink: big enough that antialiasing at the edges barely
+// counts.
+const calibrateSize = 64
+
+// printableASCII is every printable ASCII character, the glyphs -calibrate
+// chooses from unless -chars names others.
+func printableASCII() []rune {
+	rs := make([]rune, 0, 0x7f-0x20)
+	for r := rune(0x20); r < 0x7f; r++ {
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// printCalibration prints the ramp measured from the font at path, chosen
+// from glyphs and cut to levels glyphs when levels is set, and saves it as
+// the charset in the config file saveTo when that is set.
+func printCalibration(path string, glyphs []rune, levels int, saveTo string, quiet bool) error {
+	inks, err := measureInk(path, glyphs)
+	if err != nil {
+		return err
+	}
+	ramp := calibratedRamp(inks, levels)
+	if len(ramp) < 2 {
+		return errors.New("the font has fewer than 2 glyphs of different weight to choose from")
+	}
+	// Printed bare, so -chars "$(img2ascii calibrate font.ttf)" reads it
+	// back, trailing space and all.
+	fmt.Println(string(ramp))
+	if saveTo == "" {
+		return nil
+	}
+	if err := saveConfigCharset(saveTo, string(ramp)); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "saved the ramp to %s\n", saveTo)
+	}
+	return nil
+}
+
+// glyphInk is a glyph and the fraction, 0..1, of its character cell it
+// covers when drawn in some font.
+type glyphInk struct {
+	r   rune
+	ink float64
+}
+
+// measureInk draws each of glyphs from the font file at path into a cell
+// one advance wide and one line tall and returns how much of it each
+// covers. Glyphs the font lacks are left out.
+func measureInk(path string, glyphs []rune) ([]glyphInk, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: calibrateSize, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+	m := face.Metrics()
+	h := m.Height.Ceil()
+	var out []glyphInk
+	for _, r := range glyphs {
+		adv, ok := face.GlyphAdvance(r)
+		if !ok || adv <= 0 {
+			continue
+		}
+		w := adv.Ceil()
+		cell := image.NewAlpha(image.Rect(0, 0, w, h))
+		d := font.Drawer{Dst: cell, Src: image.Opaque, Face: face, Dot: fixed.P(0, m.Ascent.Ceil())}
+		d.DrawString(string(r))
+		sum := 0
+		for _, a := range cell.Pix {
+			sum += int(a)
+		}
+		out = append(out, glyphInk{r, float64(sum) / float64(255*w*h)})
+	}
+	return out, nil
+}
+
+// calibratedRamp orders inks from the most ink to the least, the dark to
+// light order -chars takes, dropping glyphs that cover the same area as one
+// already kept. With levels of at least 2 it keeps only that many, those
+// closest to even steps of coverage between the two ends.
+func calibratedRamp(inks []glyphInk, levels int) []rune {
+	sort.SliceStable(inks, func(i, j int) bool { return inks[i].ink > inks[j].ink })
+	var kept []glyphInk
+	for _, g := range inks {
+		if len(kept) > 0 && math.Abs(kept[len(kept)-1].ink-g.ink) < 1e-4 {
+			continue
+		}
+		kept = append(kept, g)
+	}
+	if levels >= 2 && levels < len(kept) {
+		hi, lo := kept[0].ink, kept[len(kept)-1].ink
+		picked := make([]glyphInk, 0, levels)
+		next := 0
+		for i := 0; i < levels; i++ {
+			target := hi - (hi-lo)*float64(i)/float64(levels-1)
+			// Later picks come from further along, so no glyph repeats and
+			// enough remain for the levels still to pick.
+			best := next
+			for j := next; j <= len(kept)-(levels-i); j++ {
+				if math.Abs(kept[j].ink-target) < math.Abs(kept[best].ink-target) {
+					best = j
+				}
+			}
+			picked = append(picked, kept[best])
+			next = best + 1
+		}
+		kept = picked
+	}
+	ramp := make([]rune, len(kept))
+	for i, g := range kept {
+		ramp[i] = g.r
+	}
+	return ramp
+}
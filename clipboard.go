@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+// clipboardWriter collects output for -clip and copies it to the system
+// clipboard when closed.
+type clipboardWriter struct {
+	bytes.Buffer
+}
+
+func (w *clipboardWriter) Close() error {
+	return copyToClipboard(w.Bytes())
+}
+
+// clipboardCommand returns the first available command that copies its
+// stdin to the clipboard: pbcopy on macOS, clip.exe on Windows, and
+// elsewhere wl-copy under Wayland, xclip, xsel, or clip.exe under WSL.
+func clipboardCommand() (path string, args []string, err error) {
+	var cands [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		cands = [][]string{{"pbcopy"}}
+	case "windows":
+		cands = [][]string{{"clip.exe"}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			cands = append(cands, []string{"wl-copy"})
+		}
+		cands = append(cands, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"}, []string{"clip.exe"})
+	}
+	for _, c := range cands {
+		if p, err := exec.LookPath(c[0]); err == nil {
+			return p, c[1:], nil
+		}
+	}
+	return "", nil, errors.New("no clipboard command found (install wl-clipboard, xclip, or xsel)")
+}
+
+// copyToClipboard puts text on the system clipboard.
+func copyToClipboard(text []byte) error {
+	path, args, err := clipboardCommand()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args...)
+	if strings.EqualFold(filepath.Base(path), "clip.exe") {
+		// clip.exe reads the console code page unless given UTF-16 with a
+		// byte order mark, which would garble ramp and Braille glyphs.
+		text = utf16LE(text)
+	}
+	cmd.Stdin = bytes.NewReader(text)
+	// Not a pipe: xclip and xsel stay in the background serving the
+	// selection, and Run would wait for them to close it.
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("clipboard: %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// utf16LE encodes UTF-8 text as little-endian UTF-16 after a byte order mark.
+func utf16LE(text []byte) []byte {
+	units := utf16.Encode([]rune(string(text)))
+	out := make([]byte, 0, 2+2*len(units))
+	out = append(out, 0xff, 0xfe)
+	for _, u := range units {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
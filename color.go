@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// colorMode selects which ANSI color escapes, if any, wrap each glyph.
+type colorMode int
+
+const (
+	colorNone colorMode = iota
+	colorTrue
+	color256
+	color16
+)
+
+// writeColor writes the foreground escape for c.
+func writeColor(buf *strings.Builder, mode colorMode, c color.RGBA) {
+	switch mode {
+	case colorTrue:
+		fmt.Fprintf(buf, "\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+	case color256:
+		fmt.Fprintf(buf, "\x1b[38;5;%dm", ansi256(c.R, c.G, c.B))
+	case color16:
+		if i := ansi16(c.R, c.G, c.B); i < 8 {
+			fmt.Fprintf(buf, "\x1b[%dm", 30+i)
+		} else {
+			fmt.Fprintf(buf, "\x1b[%dm", 90+i-8)
+		}
+	}
+}
+
+// writeBackground writes the background escape for c.
+func writeBackground(buf *strings.Builder, mode colorMode, c color.RGBA) {
+	switch mode {
+	case colorTrue:
+		fmt.Fprintf(buf, "\x1b[48;2;%d;%d;%dm", c.R, c.G, c.B)
+	case color256:
+		fmt.Fprintf(buf, "\x1b[48;5;%dm", ansi256(c.R, c.G, c.B))
+	case color16:
+		if i := ansi16(c.R, c.G, c.B); i < 8 {
+			fmt.Fprintf(buf, "\x1b[%dm", 40+i)
+		} else {
+			fmt.Fprintf(buf, "\x1b[%dm", 100+i-8)
+		}
+	}
+}
+
+// basic16 holds the 8 normal and then 8 bright ANSI colors as xterm draws
+// them by default. Terminals theme these freely, so matches are rough.
+var basic16 = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// ansi16 returns the index, 0..15, of the basic ANSI color closest to the
+// given color.
+func ansi16(r, g, b uint8) int {
+	best, bestDist := 0, -1
+	for i, c := range basic16 {
+		if d := sqDist(int(r), int(g), int(b), c[0], c[1], c[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// cubeLevels are the channel intensities of the xterm 6x6x6 color cube.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// ansi256 returns the xterm-256 palette index closest to the given color.
+// Exact grays always use the 24-step gray ramp (232-255), with the cube's
+// black (16) and white (231) covering the ends the ramp doesn't reach.
+// Other colors pick whichever of the nearest cube entry and nearest gray is
+// closer, so near-neutral tones don't band on the coarse cube.
+func ansi256(r, g, b uint8) int {
+	if r == g && g == b {
+		return grayIndex(int(r))
+	}
+	ri, gi, bi := cubeIndex(int(r)), cubeIndex(int(g)), cubeIndex(int(b))
+	cube := 16 + 36*ri + 6*gi + bi
+	cubeDist := sqDist(int(r), int(g), int(b), cubeLevels[ri], cubeLevels[gi], cubeLevels[bi])
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	gray := grayIndex(avg)
+	gv := grayValue(gray)
+	if sqDist(int(r), int(g), int(b), gv, gv, gv) < cubeDist {
+		return gray
+	}
+	return cube
+}
+
+// cubeIndex returns the index of the cube level nearest to v.
+func cubeIndex(v int) int {
+	best := 0
+	for i, l := range cubeLevels {
+		if abs(v-l) < abs(v-cubeLevels[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// grayIndex returns the palette index of the gray nearest to v.
+// The ramp runs 8, 18, ..., 238 in steps of 10.
+func grayIndex(v int) int {
+	if v < 4 {
+		return 16
+	}
+	if v > 246 {
+		return 231
+	}
+	i := (v - 8 + 5) / 10
+	if i < 0 {
+		i = 0
+	} else if i > 23 {
+		i = 23
+	}
+	return 232 + i
+}
+
+// grayValue returns the intensity of a palette index produced by grayIndex.
+func grayValue(idx int) int {
+	switch idx {
+	case 16:
+		return 0
+	case 231:
+		return 255
+	}
+	return 8 + 10*(idx-232)
+}
+
+func sqDist(r1, g1, b1, r2, g2, b2 int) int {
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
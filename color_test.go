@@ -0,0 +1,142 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"img2ascii/asciiart"
+)
+
+func TestANSI256(t *testing.T) {
+	for _, tc := range []struct {
+		r, g, b uint8
+		want    int
+	}{
+		// Exact grays take the gray ramp, with the cube's black and white
+		// past its ends.
+		{0, 0, 0, 16},
+		{3, 3, 3, 16},
+		{8, 8, 8, 232},
+		{128, 128, 128, 244},
+		{238, 238, 238, 255},
+		{250, 250, 250, 231},
+		{255, 255, 255, 231},
+		// Near grays take the ramp when it is closer than the cube...
+		{130, 128, 126, 244},
+		{60, 64, 62, 237},
+		// ...and the cube when one of its grays is.
+		{95, 95, 96, 59},
+		// Saturated colors take the cube.
+		{255, 0, 0, 196},
+		{0, 135, 215, 32},
+		{200, 40, 40, 160},
+	} {
+		if got := ansi256(tc.r, tc.g, tc.b); got != tc.want {
+			t.Errorf("ansi256(%d, %d, %d) = %d, want %d", tc.r, tc.g, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestANSI16(t *testing.T) {
+	for _, tc := range []struct {
+		c      color.RGBA
+		want   int
+		fg, bg string
+	}{
+		{color.RGBA{0, 0, 0, 255}, 0, "\x1b[30m", "\x1b[40m"},
+		{color.RGBA{190, 20, 10, 255}, 1, "\x1b[31m", "\x1b[41m"},
+		{color.RGBA{0, 180, 0, 255}, 2, "\x1b[32m", "\x1b[42m"},
+		{color.RGBA{200, 200, 30, 255}, 3, "\x1b[33m", "\x1b[43m"},
+		{color.RGBA{0, 0, 200, 255}, 4, "\x1b[34m", "\x1b[44m"},
+		{color.RGBA{190, 0, 190, 255}, 5, "\x1b[35m", "\x1b[45m"},
+		{color.RGBA{0, 200, 200, 255}, 6, "\x1b[36m", "\x1b[46m"},
+		{color.RGBA{220, 220, 220, 255}, 7, "\x1b[37m", "\x1b[47m"},
+		{color.RGBA{128, 128, 128, 255}, 8, "\x1b[90m", "\x1b[100m"},
+		{color.RGBA{255, 40, 40, 255}, 9, "\x1b[91m", "\x1b[101m"},
+		{color.RGBA{40, 255, 40, 255}, 10, "\x1b[92m", "\x1b[102m"},
+		{color.RGBA{255, 255, 60, 255}, 11, "\x1b[93m", "\x1b[103m"},
+		{color.RGBA{90, 90, 250, 255}, 12, "\x1b[94m", "\x1b[104m"},
+		{color.RGBA{255, 30, 255, 255}, 13, "\x1b[95m", "\x1b[105m"},
+		{color.RGBA{30, 255, 255, 255}, 14, "\x1b[96m", "\x1b[106m"},
+		{color.RGBA{255, 255, 255, 255}, 15, "\x1b[97m", "\x1b[107m"},
+		// Dark tones fall to black, and midtone grays to bright black.
+		{color.RGBA{40, 30, 30, 255}, 0, "\x1b[30m", "\x1b[40m"},
+		{color.RGBA{100, 110, 120, 255}, 8, "\x1b[90m", "\x1b[100m"},
+	} {
+		if got := ansi16(tc.c.R, tc.c.G, tc.c.B); got != tc.want {
+			t.Errorf("ansi16(%d, %d, %d) = %d, want %d", tc.c.R, tc.c.G, tc.c.B, got, tc.want)
+		}
+		var fg, bg strings.Builder
+		writeColor(&fg, color16, tc.c)
+		writeBackground(&bg, color16, tc.c)
+		if fg.String() != tc.fg || bg.String() != tc.bg {
+			t.Errorf("%v: escapes %q and %q, want %q and %q", tc.c, fg.String(), bg.String(), tc.fg, tc.bg)
+		}
+	}
+}
+
+func TestANSIRowsGray256(t *testing.T) {
+	// A pure gray and a near gray of the same brightness pick the same
+	// glyph and the same ramp gray, so the row needs one escape.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{128, 128, 128, 255})
+	img.Set(1, 0, color.RGBA{130, 128, 126, 255})
+	g, err := asciiart.RenderGrid(img, asciiart.Options{Width: 2, Height: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g[0][0].Ch != '=' || g[0][1].Ch != '=' {
+		t.Errorf("glyphs %q and %q, want '=' for both", g[0][0].Ch, g[0][1].Ch)
+	}
+	if g[0][1].FG != (color.RGBA{130, 128, 126, 255}) {
+		t.Errorf("near gray cell colored %v, want its own color", g[0][1].FG)
+	}
+	if got, want := ansiRows(g, color256)[0], "\x1b[38;5;244m==\x1b[0m"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestANSIRowsSelfContained(t *testing.T) {
+	// Every cell has the same colors, so only a row that starts afresh
+	// repeats its escapes after the row above.
+	red, blue := color.RGBA{200, 30, 30, 255}, color.RGBA{30, 30, 200, 255}
+	solid := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	striped := image.NewRGBA(image.Rect(0, 0, 4, 6))
+	for y := range 6 {
+		for x := range 4 {
+			solid.Set(x, y/2, red)
+			if y%2 == 0 {
+				striped.Set(x, y, red)
+			} else {
+				striped.Set(x, y, blue)
+			}
+		}
+	}
+	plain, err := asciiart.RenderGrid(solid, asciiart.Options{Width: 4, Height: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	halfblock, err := asciiart.RenderHalfBlock(striped, asciiart.Options{Width: 4, Height: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []asciiart.Grid{plain, halfblock} {
+		for _, mode := range []colorMode{colorTrue, color256, color16} {
+			for y, row := range ansiRows(g, mode) {
+				c := g[y][0]
+				want := escape(writeColor, mode, c.FG)
+				if c.HasBG {
+					want += escape(writeBackground, mode, c.BG)
+				}
+				if !strings.HasPrefix(row, want) {
+					t.Errorf("mode %d, HasBG %t, row %d: %q doesn't start with %q", mode, c.HasBG, y, row, want)
+				}
+				if !strings.HasSuffix(row, "\x1b[0m") {
+					t.Errorf("mode %d, HasBG %t, row %d: %q doesn't end with a reset", mode, c.HasBG, y, row)
+				}
+			}
+		}
+	}
+}
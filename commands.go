@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"unicode/utf8"
+)
+
+// commands are the subcommands that may come first on the command line.
+// Without one, the arguments are render's, as they were before there were
+// subcommands.
+var commands = map[string]bool{
+	"render":    true,
+	"info":      true,
+	"serve":     true,
+	"calibrate": true,
+}
+
+// splitCommand takes the subcommand off the front of args, returning
+// "render" when there is none. A file by the command's name is still taken
+// for an image argument.
+func splitCommand(args []string) (cmd string, rest []string) {
+	if len(args) > 0 && commands[args[0]] && !fileExists(args[0]) {
+		return args[0], args[1:]
+	}
+	return "render", args
+}
+
+// runInfo prints the format, size, and frame count of each image named in
+// args, without rendering it.
+func runInfo(args []string) {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	noAutorotate := fs.Bool("no-autorotate", false, "report the stored size of JPEG and TIFF photos, ignoring their EXIF orientation")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: img2ascii info [flags] image ...")
+		fmt.Fprintln(fs.Output(), "\nPrint each image's format, size in pixels, and frame count.\n\nflags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	// An SVG is reported at its intrinsic size.
+	failed := 0
+	for _, p := range fs.Args() {
+		if err := printInfo(p, !*noAutorotate, svgPixels); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", p, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printInfo decodes the image at p and prints a line describing it, e.g.
+// "cat.gif: gif 320x240, 12 frames".
+func printInfo(p string, autorotate bool, svgSize func(w, h float64) (int, int, error)) error {
+	f, err := openInput(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Only the first page of a TIFF is measured.
+	anim, err := decodeAnimation(f, decodeOptions{autorotate: autorotate, svgSize: svgSize})
+	if err != nil {
+		return err
+	}
+	b := anim.frames[0].Bounds()
+	line := fmt.Sprintf("%s %dx%d", formatLine(p, anim.format), b.Dx(), b.Dy())
+	if n := len(anim.frames); n > 1 {
+		line += fmt.Sprintf(", %d frames", n)
+	}
+	fmt.Println(line)
+	return nil
+}
+
+// runCalibrate prints, and optionally saves, a ramp measured from the font
+// named in args; it is the -calibrate flag as a command.
+func runCalibrate(args []string) {
+	fs := flag.NewFlagSet("calibrate", flag.ExitOnError)
+	chars := fs.String("chars", "", "glyphs to choose from (default printable ASCII)")
+	levels := fs.Int("levels", 0, "keep this many glyphs, spread evenly over the ramp (0 keeps every distinct weight)")
+	save := fs.Bool("save", false, "also save the ramp as the charset in the -config file")
+	defaultConfig, _ := defaultConfigFile()
+	configFile := fs.String("config", defaultConfig, "JSON config file -save writes to")
+	quiet := fs.Bool("quiet", false, "don't report where -save wrote the ramp")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: img2ascii calibrate [flags] font.ttf")
+		fmt.Fprintln(fs.Output(), "\nPrint a -chars ramp ordered by how much of its cell each glyph of the font inks.\n\nflags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *levels < 0 || *levels == 1 {
+		fail(errors.New("-levels must be 0 or at least 2"))
+	}
+	glyphs := printableASCII()
+	if *chars != "" {
+		if utf8.RuneCountInString(*chars) < 2 {
+			fail(errors.New("-chars must contain at least 2 characters"))
+		}
+		glyphs = []rune(*chars)
+	}
+	saveTo := ""
+	if *save {
+		if *configFile == "" {
+			fail(errors.New("-save needs a -config file"))
+		}
+		saveTo = *configFile
+	}
+	if err := printCalibration(fs.Arg(0), glyphs, *levels, saveTo, *quiet); err != nil {
+		fail(fmt.Errorf("calibrate: %w", err))
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"image/color"
+
+	"img2ascii/asciiart"
+)
+
+// mutedColor draws separators and captions; mid gray reads on both light and
+// dark backgrounds.
+var mutedColor = color.RGBA{0x80, 0x80, 0x80, 0xff}
+
+// separator is the column drawn between the two images of a comparison.
+var separator = []asciiart.Cell{
+	{Ch: ' '},
+	{Ch: '|', FG: mutedColor},
+	{Ch: ' '},
+}
+
+// sideBySide joins a and b into one grid, a on the left, with a separator
+// column between them. The shorter grid is padded with blank rows at the
+// bottom so every row keeps both halves aligned.
+func sideBySide(a, b asciiart.Grid) asciiart.Grid {
+	aw, bw := gridWidth(a), gridWidth(b)
+	g := make(asciiart.Grid, max(len(a), len(b)))
+	for y := range g {
+		row := make([]asciiart.Cell, 0, aw+len(separator)+bw)
+		row = append(row, paddedRow(a, y, aw)...)
+		row = append(row, separator...)
+		row = append(row, paddedRow(b, y, bw)...)
+		g[y] = row
+	}
+	return g
+}
+
+// gridWidth returns the number of columns in g.
+func gridWidth(g asciiart.Grid) int {
+	if len(g) == 0 {
+		return 0
+	}
+	return len(g[0])
+}
+
+// paddedRow returns row y of g, or w blank cells past its last row.
+func paddedRow(g asciiart.Grid, y, w int) []asciiart.Cell {
+	if y < len(g) {
+		return g[y]
+	}
+	return blankCells(w)
+}
+
+// blankCells returns w uncolored spaces.
+func blankCells(w int) []asciiart.Cell {
+	blank := make([]asciiart.Cell, w)
+	for i := range blank {
+		blank[i].Ch = ' '
+	}
+	return blank
+}
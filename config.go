@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// config holds defaults for a handful of flags, read from a JSON file such
+// as {"width": 100, "invert": true, "charset": " .:-=+*#%@", "aspect": 0.45,
+// "color": "truecolor"}. Settings left out of the file are nil.
+type config struct {
+	Width   *int     `json:"width,omitempty"`
+	Invert  *bool    `json:"invert,omitempty"`
+	Charset *string  `json:"charset,omitempty"`
+	Aspect  *float64 `json:"aspect,omitempty"`
+	Color   *string  `json:"color,omitempty"` // none, truecolor, 256, or 16
+}
+
+// defaultConfigFile returns the config file read when -config isn't given,
+// under the user's config directory.
+func defaultConfigFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "img2ascii", "config.json"), nil
+}
+
+// loadConfig reads the config file at p. Unknown settings are an error, so
+// a misspelled one isn't silently ignored. Errors leave out p, which the
+// caller reports.
+func loadConfig(p string) (config, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			err = pe.Err
+		}
+		return config{}, err
+	}
+	var c config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return config{}, err
+	}
+	return c, nil
+}
+
+// saveConfigCharset sets the charset in the config file at p, keeping its
+// other settings, and creates the file if it doesn't exist yet.
+func saveConfigCharset(p, charset string) error {
+	c, err := loadConfig(p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	c.Charset = &charset
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(data, '\n'), 0o644)
+}
+
+// applyConfig sets each flag that c has a setting for, unless the command
+// line or a preset already set it or a flag it conflicts with; those always
+// win. A setting the flag would reject is reported to warn and skipped.
+func applyConfig(flags *flag.FlagSet, c config, warn func(error)) {
+	set := func(name, value string) {
+		if !ruledOut(flags, name) {
+			flags.Set(name, value)
+		}
+	}
+	if c.Width != nil {
+		if *c.Width <= 0 {
+			warn(errors.New("width must be > 0"))
+		} else {
+			set("w", fmt.Sprint(*c.Width))
+		}
+	}
+	if c.Invert != nil {
+		set("invert", fmt.Sprint(*c.Invert))
+	}
+	if c.Charset != nil {
+		if utf8.RuneCountInString(*c.Charset) < 2 {
+			warn(errors.New("charset must contain at least 2 characters"))
+		} else {
+			set("chars", *c.Charset)
+		}
+	}
+	if c.Aspect != nil {
+		if *c.Aspect <= 0 {
+			warn(errors.New("aspect must be > 0"))
+		} else {
+			set("aspect", fmt.Sprint(*c.Aspect))
+		}
+	}
+	if c.Color != nil {
+		switch *c.Color {
+		case "none":
+		case "truecolor":
+			set("color", "true")
+		case "256":
+			set("color256", "true")
+		case "16":
+			set("color16", "true")
+		default:
+			warn(fmt.Errorf("unknown color %q (want none, truecolor, 256, or 16)", *c.Color))
+		}
+	}
+}
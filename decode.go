@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// decodeError is a failure to make an image of data that was read fine:
+// the file is empty, cut short, corrupt, or not an image at all. Errors
+// reading the data are returned as they are instead, so the two can be told
+// apart.
+type decodeError struct {
+	format string // the format the data looked like, or "" if none
+	empty  bool
+	err    error
+}
+
+func (e *decodeError) Error() string {
+	switch {
+	case e.empty:
+		return "empty file"
+	case e.format == "":
+		return "not an image in any supported format"
+	case errors.Is(e.err, io.ErrUnexpectedEOF):
+		return fmt.Sprintf("truncated %s data", e.format)
+	default:
+		// Decoders start their messages with their own name.
+		return fmt.Sprintf("invalid %s data: %s", e.format, strings.TrimPrefix(e.err.Error(), e.format+": "))
+	}
+}
+
+func (e *decodeError) Unwrap() error { return e.err }
+
+// readRecorder passes reads through to r, keeping the first error other
+// than io.EOF.
+type readRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (rr *readRecorder) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	if err != nil && err != io.EOF && rr.err == nil {
+		rr.err = err
+	}
+	return n, err
+}
+
+// sniffFormat names the image format whose signature head starts with, or
+// returns "" when it matches none of those decodeAnimation handles.
+func sniffFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case bytes.HasPrefix(head, []byte("\xff\xd8")):
+		return "jpeg"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "gif"
+	case bytes.HasPrefix(head, []byte("BM")):
+		return "bmp"
+	case tiffByteOrder(head) != nil:
+		return "tiff"
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return "webp"
+	case isSVG(head):
+		return "svg"
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+package main
+
+import "img2ascii/asciiart"
+
+// doubleCells returns g with every cell repeated once to its right, colors
+// and all, so each sample covers two terminal columns.
+func doubleCells(g asciiart.Grid) asciiart.Grid {
+	out := make(asciiart.Grid, len(g))
+	for y, row := range g {
+		out[y] = make([]asciiart.Cell, 0, 2*len(row))
+		for _, c := range row {
+			out[y] = append(out[y], c, c)
+		}
+	}
+	return out
+}
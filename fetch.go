@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// fetchTimeout bounds the whole request, including reading the body.
+const fetchTimeout = 30 * time.Second
+
+const userAgent = "img2ascii (+https://github.com/Ollie1o1/imgtoascii)"
+
+// isURL reports whether p should be fetched over HTTP rather than opened.
+func isURL(p string) bool {
+	lp := strings.ToLower(p)
+	return strings.HasPrefix(lp, "http://") || strings.HasPrefix(lp, "https://")
+}
+
+// openInput opens p for decoding, fetching it when it is an http(s) URL.
+func openInput(p string) (io.ReadCloser, error) {
+	if !isURL(p) {
+		return os.Open(p)
+	}
+	return fetchURL(p)
+}
+
+// fetchURL GETs url and returns the response body. Responses other than 200,
+// and bodies that are declared as text (such as an HTML error page), are
+// rejected.
+func fetchURL(url string) (io.ReadCloser, error) {
+	return fetchURLContext(context.Background(), url)
+}
+
+// fetchURLContext is like fetchURL but gives up when ctx is done.
+func fetchURLContext(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "image/*")
+
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: %s", url, resp.Status)
+	}
+	if mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && strings.HasPrefix(mt, "text/") {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: unsupported content type %s", url, mt)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+)
+
+// Terminal graphics protocols -inline can speak.
+const (
+	inlineITerm2 = "iterm2" // OSC 1337 File, also understood by WezTerm
+	inlineKitty  = "kitty"  // the kitty graphics protocol
+)
+
+// inlineProtocol picks the graphics protocol of the terminal the
+// environment describes, if it has one.
+func inlineProtocol() (string, error) {
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty":
+		return inlineKitty, nil
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("TERM_PROGRAM") == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return inlineITerm2, nil
+	}
+	return "", errors.New("-inline needs a terminal that shows images inline: iTerm2, WezTerm, or kitty")
+}
+
+// kittyChunk is the most base64 data the kitty protocol takes per escape.
+const kittyChunk = 4096
+
+// writeInline writes the image data, in the given format, to out as an
+// inline image in protocol, cols cells wide or, when cols is 0, rows cells
+// tall. iTerm2 gets the file as it is; kitty only takes PNG, so other
+// formats are decoded and re-encoded first.
+func writeInline(out *bufio.Writer, data []byte, format, protocol string, cols, rows int) error {
+	if protocol == inlineITerm2 {
+		size := "width=auto"
+		switch {
+		case cols > 0:
+			size = fmt.Sprintf("width=%d", cols)
+		case rows > 0:
+			size = fmt.Sprintf("height=%d", rows)
+		}
+		fmt.Fprintf(out, "\x1b]1337;File=inline=1;size=%d;%s;preserveAspectRatio=1:%s\a\n", len(data), size, base64.StdEncoding.EncodeToString(data))
+		return nil
+	}
+	if format != "png" {
+		anim, err := decodeAnimation(bytes.NewReader(data), decodeOptions{autorotate: true})
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, anim.frames[0]); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+	size := ""
+	switch {
+	case cols > 0:
+		size = fmt.Sprintf(",c=%d", cols)
+	case rows > 0:
+		size = fmt.Sprintf(",r=%d", rows)
+	}
+	enc := base64.StdEncoding.EncodeToString(data)
+	for first := true; first || enc != ""; first = false {
+		chunk := enc[:min(len(enc), kittyChunk)]
+		enc = enc[len(chunk):]
+		more := 0
+		if enc != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(out, "\x1b_Ga=T,f=100%s,m=%d;%s\x1b\\", size, more, chunk)
+		} else {
+			fmt.Fprintf(out, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	out.WriteByte('\n')
+	return nil
+}
+
+// readInput reads all of the image at p, a path or URL.
+func readInput(p string) ([]byte, error) {
+	f, err := openInput(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+
+	"img2ascii/asciiart"
+)
+
+// jsonGrid is the document written by -format json.
+type jsonGrid struct {
+	Width  int          `json:"width"`
+	Height int          `json:"height"`
+	Cells  [][]jsonCell `json:"cells"`
+}
+
+// jsonCell is one character. Colors are #RRGGBB and only present when
+// colored; bg is set for half-block cells.
+type jsonCell struct {
+	Ch    string `json:"ch"`
+	Color string `json:"color,omitempty"`
+	BG    string `json:"bg,omitempty"`
+}
+
+// writeJSON writes g as a single JSON object followed by a newline.
+func writeJSON(out *bufio.Writer, g asciiart.Grid, colored bool) error {
+	doc := jsonGrid{Height: len(g), Cells: make([][]jsonCell, len(g))}
+	if len(g) > 0 {
+		doc.Width = len(g[0])
+	}
+	for y, row := range g {
+		cells := make([]jsonCell, len(row))
+		for x, c := range row {
+			cells[x].Ch = string(c.Ch)
+			if colored {
+				cells[x].Color = hexColor(c.FG)
+				if c.HasBG {
+					cells[x].BG = hexColor(c.BG)
+				}
+			}
+		}
+		doc.Cells[y] = cells
+	}
+	return json.NewEncoder(out).Encode(doc)
+}
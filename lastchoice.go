@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lastChoiceFile returns the file remembering the image last picked from
+// each directory, under the user's cache directory.
+func lastChoiceFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "img2ascii", "last-choice.json"), nil
+}
+
+// loadLastChoices returns the remembered picks, absolute image paths keyed by
+// absolute directory. A missing or corrupt file gives an empty map.
+func loadLastChoices() map[string]string {
+	choices := map[string]string{}
+	p, err := lastChoiceFile()
+	if err != nil {
+		return choices
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return choices
+	}
+	if err := json.Unmarshal(data, &choices); err != nil || choices == nil {
+		return map[string]string{}
+	}
+	return choices
+}
+
+// lastChoiceIndex returns the position in cands of the image last picked
+// from their directory, if it is one of them.
+func lastChoiceIndex(cands []string) (int, bool) {
+	last, ok := loadLastChoices()[commonDir(cands)]
+	if !ok {
+		return 0, false
+	}
+	for i, c := range cands {
+		if absPath(c) == last {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// rememberChoice records choice as the last image picked from dir. It is a
+// convenience, so failing to save is not reported.
+func rememberChoice(dir, choice string) {
+	p, err := lastChoiceFile()
+	if err != nil {
+		return
+	}
+	choices := loadLastChoices()
+	choices[dir] = choice
+	data, err := json.MarshalIndent(choices, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return
+	}
+	os.WriteFile(p, append(data, '\n'), 0o644)
+}
+
+// absPath returns p made absolute, or p itself if that fails.
+func absPath(p string) string {
+	if a, err := filepath.Abs(p); err == nil {
+		return a
+	}
+	return p
+}
+
+// commonDir returns the deepest absolute directory holding all of paths.
+func commonDir(paths []string) string {
+	dir := filepath.Dir(absPath(paths[0]))
+	for _, p := range paths[1:] {
+		p = absPath(p)
+		for !inDir(p, dir) {
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	return dir
+}
+
+// inDir reports whether the absolute path p lies under dir.
+func inDir(p, dir string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -6,79 +6,1221 @@ import (
 	"flag"
 	"fmt"
 	"image"
-	_ "image/bmp"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
-	_ "image/tiff"
+	"io"
+	"io/fs"
 	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"img2ascii/asciiart"
 )
 
 func main() {
-	inPath := flag.String("i", "", "path to input image or directory (optional; interactive when omitted)")
-	width := flag.Int("w", 80, "output width in characters")
-	invert := flag.Bool("invert", false, "invert brightness mapping")
-	glob := flag.String("glob", "", "optional glob to match images (e.g. *.png)")
-	fromStdin := flag.Bool("stdin", false, "read an image path from stdin (first non-empty line)")
-	interactive := flag.Bool("interactive", true, "prompt to choose when multiple images are found or no input provided")
-	flag.Parse()
-
-	if *width <= 0 {
-		fail(errors.New("-w must be > 0"))
+	cmd, args := splitCommand(os.Args[1:])
+	switch cmd {
+	case "info":
+		runInfo(args)
+	case "calibrate":
+		runCalibrate(args)
+	default:
+		runRender(flag.NewFlagSet(cmd, flag.ExitOnError), args, cmd == "serve")
 	}
+}
 
-	// Resolve which image to open.
-	imgPath, err := resolveInput(*inPath, *glob, *fromStdin, *interactive)
+// runRender parses the render flags from args into flags and renders, or
+// under -serve (or the serve command, which takes the address as its
+// argument) serves, the images they name.
+func runRender(flags *flag.FlagSet, args []string, serveCmd bool) {
+	// Flags about finding, writing, and showing images are left off the
+	// serve command's set, so it neither takes nor lists them; they keep
+	// their defaults there.
+	renderOnly := flags
+	if serveCmd {
+		renderOnly = flag.NewFlagSet("render", flag.ContinueOnError)
+	}
+	inPath := renderOnly.String("i", "", "path or http(s) URL of input image, or a directory (optional; the input is taken from -stdin, else -i, else -glob, else $"+inputEnv+", else chosen interactively from the current directory)")
+	inPath2 := renderOnly.String("i2", "", "path or http(s) URL of a second image rendered beside the first at the same height, for comparisons")
+	width := flags.Int("w", 80, "output width in characters (defaults to the terminal width when stdout is a terminal)")
+	maxWidth := flags.Int("max-width", 2000, "largest output width, and height, in characters; bigger grids, however asked for, are shrunk to fit with a warning (0 for no limit)")
+	scale := renderOnly.Float64("scale", 0, "output width as a fraction of the image's pixel width (e.g. 0.25); overrides -w")
+	height := flags.Int("h", 0, "output height in rows; overrides -aspect, and derives the width when -w is omitted")
+	aspect := flags.Float64("aspect", asciiart.DefaultCharAspect, "character cell width/height ratio used to derive the row count (smaller values produce fewer rows)")
+	invert := flags.Bool("invert", false, "invert brightness mapping")
+	autoInvert := renderOnly.Bool("auto-invert", false, "ask the terminal for its background color and set -invert when it is dark (unchanged if the terminal doesn't answer)")
+	glob := renderOnly.String("glob", "", "optional glob to match images (e.g. *.png)")
+	fromStdin := renderOnly.Bool("stdin", false, "read an image path from stdin (first non-empty line)")
+	showStats := renderOnly.Bool("stats", false, "after rendering, print the detected format, bytes read, source and grid sizes, and how long decoding, sampling, picking glyphs, and writing took to stderr")
+	showFormat := renderOnly.Bool("show-format", false, "print which decoder read each image (png, jpeg, gif, ...), noting a mismatched extension, and exit without rendering")
+	fromFile := renderOnly.String("from-file", "", "render the images listed in this file, one path per line (blank lines and # comments skipped, relative paths taken from the file's directory), like image arguments")
+	interactive := renderOnly.Bool("interactive", true, "prompt to choose when multiple images are found or no input provided")
+	strict := renderOnly.Bool("strict", false, "when a choice must be made but stdin is not a terminal to prompt on, fail instead of taking the first candidate")
+	quiet := flags.Bool("quiet", false, "never prompt (take the first candidate, like -interactive=false) and print nothing to stderr but errors")
+	outPath := renderOnly.String("o", "", "write ASCII output to this file instead of stdout")
+	clip := renderOnly.Bool("clip", false, "copy the output to the system clipboard instead of printing it (plain text unless -force-color)")
+	truecolor := flags.Bool("color", false, "emit 24-bit truecolor ANSI escapes using each pixel's color")
+	use256 := flags.Bool("color256", false, "emit xterm 256-color ANSI escapes (for terminals without truecolor)")
+	use16 := flags.Bool("color16", false, "emit the 16 basic ANSI colors, the nearest to each pixel (for terminals such as TERM=ansi)")
+	forceColor := renderOnly.Bool("force-color", false, "keep -color, -color256, -color16, and -shade escapes in text written to stdout when it is not a terminal")
+	sample := flags.String("sample", "nearest", "downsampling mode: nearest (fast), average (box filter, keeps thin lines), or lanczos (Lanczos-3 resampling, keeps fine detail)")
+	chars := flags.String("chars", asciiart.DefaultCharset, "character ramp ordered from dark to light (at least 2 characters)")
+	levels := flags.Int("levels", 0, "number of glyphs used from the -chars ramp, spread evenly from its first to its last (0 uses them all; 2 approximates -bw)")
+	supersample := flags.Int("supersample", 1, "average an NxN grid of samples in each cell, for -sample nearest with the character ramp; 1 takes the one pixel at the cell's center")
+	gamma := flags.Float64("gamma", 1, "luminance gamma applied before mapping to glyphs (>1 brightens midtones)")
+	tone := flags.String("tone", "", "piecewise-linear tone curve applied to luminance after -gamma, as in,out points separated by semicolons (e.g. 0,0;64,96;255,255 lifts shadows)")
+	contrast := flags.Float64("contrast", 1, "luminance multiplier around the 128 midpoint (>1 increases contrast)")
+	brightness := flags.Float64("brightness", 0, "added to luminance after -contrast, -255..255")
+	lumWeights := flags.String("lum-weights", "0.2126,0.7152,0.0722", "comma-separated R,G,B luminance weights (normalized to sum to 1)")
+	blur := flags.Float64("blur", 0, "standard deviation in source pixels of a Gaussian blur applied before sampling, to smooth noisy photos (0 disables)")
+	sharpenAmount := flags.Float64("sharpen", 0, "unsharp mask strength applied to the downsampled luminance before choosing glyphs (0 disables; try 0.5-2 for small renders of text)")
+	autoContrast := flags.Bool("autocontrast", false, "stretch the image's luminance range to the full ramp")
+	autoContrastClip := flags.Float64("autocontrast-clip", 0, "percentage 0..50 of darkest and brightest cells ignored as outliers by -autocontrast")
+	equalize := flags.Bool("equalize", false, "equalize the luminance histogram before choosing glyphs (applied after -gamma, -contrast, -brightness and -autocontrast)")
+	bg := flags.String("bg", "#000000", "background as #RRGGBB, black, white, or gray that translucent pixels are composited over before choosing glyphs")
+	transparentSpace := flags.Bool("transparent-space", false, "draw a space for pixels whose alpha is below -alpha-threshold")
+	alphaThreshold := flags.Int("alpha-threshold", 128, "alpha cutoff 0..255 for -transparent-space")
+	perceptual := flags.Bool("perceptual", false, "measure brightness as perceptual CIE L* from linearized sRGB, spreading midtones over more of the ramp")
+	alphaMask := flags.Bool("alpha", false, "render the alpha channel instead of brightness, to check transparency masks: opaque pixels take the densest glyph, transparent ones a space")
+	var grayscale grayFlag
+	flags.Var(&grayscale, "grayscale", "read brightness without color weighting, for gray scans: -grayscale for the mean of R, G and B, -grayscale=r, g, or b for that channel alone")
+	var dither ditherFlag
+	flags.Var(&dither, "dither", "dither to smooth banding across the character ramp: -dither or -dither=fs for Floyd-Steinberg error diffusion, -dither=ordered for a Bayer matrix")
+	ditherSize := flags.Int("dither-size", 4, "Bayer matrix size for -dither=ordered: 2, 4, or 8")
+	braille := flags.Bool("braille", false, "render with Unicode Braille dots (2x4 dots per character) instead of the character ramp")
+	halfblock := flags.Bool("halfblock", false, "render two pixel rows per character with colored half blocks (requires -color, -color256, or -color16)")
+	pixel := flags.Bool("pixel", false, "draw each source pixel as one colored full block, without resampling, for pixel art such as sprites (requires -color, -color256, or -color16; ignores -w, -h, -scale, and -aspect)")
+	format := flags.String("format", "text", "output format: text, html, svg, json, png, or pgm (the sampled luminance grid)")
+	cellSize := flags.Float64("cell-size", 8, "character cell width in pixels for -format svg and png (height follows the character aspect), and per column when rasterizing SVG input")
+	svgBG := renderOnly.String("svg-bg", "", "background fill for -format svg as #RRGGBB (default transparent)")
+	pngBG := renderOnly.String("png-bg", "#FFFFFF", "background color for -format png as #RRGGBB")
+	edges := flags.Bool("edges", false, "draw outlines with |, -, / and \\ along edges found by a Sobel filter")
+	edgeThreshold := flags.Float64("edge-threshold", 100, "minimum Sobel gradient magnitude drawn by -edges (higher keeps only stronger edges)")
+	bw := flags.Bool("bw", false, "pure black-and-white: pixels darker than -threshold use the first -bw-chars glyph, the rest the second")
+	bwChars := flags.String("bw-chars", asciiart.DefaultBWChars, "dark and light glyph pair for -bw")
+	threshold := flags.Int("threshold", 128, "brightness cutoff 0..255 for -braille and -bw; darker pixels set a dot or take the dark glyph")
+	frame := renderOnly.Int("frame", 0, "frame of an animated GIF, or page of a multi-page TIFF, to render (0-based)")
+	allFrames := renderOnly.Bool("all-frames", false, "render every frame of an animated GIF, or page of a multi-page TIFF, separated by form feeds")
+	sparkline := renderOnly.Bool("sparkline", false, "add sparklines of the mean brightness of each row, down the right side, and of each column, along the bottom")
+	border := renderOnly.Bool("border", false, "draw a box around text output")
+	borderStyle := renderOnly.String("border-style", "single", "box style for -border: single, double, rounded, or ascii")
+	page := renderOnly.Bool("page", false, "when writing text to a terminal, show one screenful at a time and wait for a key between screens")
+	play := renderOnly.Bool("play", false, "play an animated GIF in the terminal using its frame delays and loop count")
+	list := renderOnly.Bool("list", false, "print the candidate images and which one would be chosen, then exit without rendering")
+	crop := renderOnly.String("crop", "", "render only the region x,y,w,h in source pixels (applied before -rotate and -flip)")
+	rotate := renderOnly.Int("rotate", 0, "rotate the image clockwise by 0, 90, 180, or 270 degrees (after EXIF autorotation, before -flip)")
+	flip := renderOnly.String("flip", "", "mirror the image after -rotate: h (left-right), v (top-bottom), or hv (both)")
+	inline := renderOnly.Bool("inline", false, "show the original image itself with the terminal's inline image protocol (iTerm2, WezTerm, or kitty) instead of ASCII, -w columns wide")
+	noWrapWarn := renderOnly.Bool("no-wrap-warn", false, "don't warn when text output is wider than the terminal it goes to")
+	pad := renderOnly.Bool("pad", false, "right-pad every row with spaces to the width of the widest, for pasting into fixed-width layouts")
+	shade := renderOnly.Bool("shade", false, "without color, add depth with ANSI intensity: bright characters bold, dark ones dim")
+	doubleWidth := renderOnly.Bool("double-width", false, "draw every character twice side by side, sampling half as many columns at twice the -aspect, for terminals whose cells are very tall (-w still counts output columns)")
+	flipY := flags.Bool("flip-y", false, "emit the output rows bottom to top, for images stored with a bottom-left origin (OpenGL-style); independent of -rotate and -flip")
+	trim := renderOnly.Bool("trim", false, "cut uniform borders, such as black bars or white matting, off the edges before rendering (after -crop, -rotate, and -flip)")
+	trimTolerance := renderOnly.Float64("trim-tolerance", 16, "largest luminance difference, 0..255, from an edge's color that -trim still counts as border")
+	noAutorotate := renderOnly.Bool("no-autorotate", false, "ignore the EXIF orientation of JPEG and TIFF photos")
+	recursive := renderOnly.Bool("recursive", false, "also look for images in subdirectories when -i is a directory")
+	batch := renderOnly.Bool("batch", false, "render every image in the -i directory to its own file instead of picking one")
+	montageSheet := renderOnly.Bool("montage", false, "tile a captioned thumbnail of every image in the -i directory into one contact sheet")
+	cols := renderOnly.Int("cols", 4, "thumbnails per row for -montage")
+	outDir := renderOnly.String("outdir", "", "directory for -batch output files (default: next to each image)")
+	watch := renderOnly.Bool("watch", false, "keep running and re-render, clearing the screen, whenever the input file is saved")
+	watchInterval := renderOnly.Duration("watch-interval", 500*time.Millisecond, "how often -watch checks the input file for changes")
+	serveAddr := renderOnly.String("serve", "", "serve rendered art over HTTP on this address (e.g. :8080) at GET /render?url=...&w=...&invert=...&format=text|html, instead of rendering one image")
+	calibrate := renderOnly.String("calibrate", "", "print a -chars ramp measured from the TrueType or OpenType font at this path, ordered by how much of its cell each glyph inks, and exit; picks from -chars when given, else printable ASCII, and keeps -levels glyphs when set")
+	calibrateSave := renderOnly.Bool("calibrate-save", false, "also save the -calibrate ramp as the charset in the -config file")
+	showVersion := renderOnly.Bool("version", false, "print the version, commit, and Go version of this build and exit")
+	jobs := flags.Int("jobs", 0, "maximum number of rows rendered concurrently (0 uses every CPU)")
+	defaultConfig, _ := defaultConfigFile()
+	preset := flags.String("preset", "", "apply a bundle of options that other flags can override: photo (-sample average -gamma 2.2 -autocontrast), lineart (-edges), blocks (-halfblock -color), or classic (the defaults)")
+	configFile := flags.String("config", defaultConfig, "JSON file with defaults for -w, -invert, -chars, -aspect, and the color mode, which flags override (empty reads none)")
+	flags.Usage = func() {
+		w := flags.Output()
+		if serveCmd {
+			fmt.Fprintln(w, "usage: img2ascii serve [flags] address")
+		} else {
+			fmt.Fprintln(w, "usage: img2ascii [render] [flags] [image ...]")
+			fmt.Fprintln(w, "       img2ascii info|serve|calibrate -help")
+		}
+		fmt.Fprintln(w, "\nflags:")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	// given holds the flags set on the command line, which a preset and the
+	// config file don't count as: they only fill in the rest.
+	given := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	if *showVersion {
+		writeVersion(os.Stdout)
+		return
+	}
+
+	// A preset, like the config file after it, only fills in flags missing
+	// from the command line, and drops values that conflict with them, such
+	// as -edges against -braille.
+	if *preset != "" {
+		p, ok := presets[*preset]
+		if !ok {
+			fail(fmt.Errorf("unknown -preset %q (want photo, lineart, blocks, or classic)", *preset))
+		}
+		applyPreset(flags, p)
+	}
+
+	// The config file only fills in flags missing from the command line. A
+	// bad one is reported but doesn't stop the run, and the default file
+	// need not exist.
+	if *configFile != "" {
+		warn := func(err error) {
+			if !*quiet {
+				fmt.Fprintf(os.Stderr, "warning: %s: %v\n", *configFile, err)
+			}
+		}
+		if c, err := loadConfig(*configFile); err == nil {
+			applyConfig(flags, c, warn)
+		} else if given["config"] || !errors.Is(err, fs.ErrNotExist) {
+			warn(err)
+		}
+	}
+
+	// Images may also be given as arguments. A lone argument is the same as
+	// -i, so it may name a directory or a URL too.
+	files := flags.Args()
+	if serveCmd {
+		if len(files) != 1 {
+			fail(errors.New("serve takes the address to listen on, such as :8080, as its one argument"))
+		}
+		*serveAddr, files = files[0], nil
+	}
+	for _, a := range files {
+		// Parsing stops at the first argument, so later flags would be
+		// taken for file names.
+		name, _, _ := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		if strings.HasPrefix(a, "-") && flags.Lookup(name) != nil {
+			fail(fmt.Errorf("flag %s must come before the image arguments", a))
+		}
+	}
+	if len(files) > 0 && (files[0] == "fs" || files[0] == "ordered") && given["dither"] && !fileExists(files[0]) {
+		// -dither is a bool flag, so its mode must be joined to it.
+		fail(fmt.Errorf("write -dither=%s; a bare -dither means fs", files[0]))
+	}
+	if len(files) > 0 && (files[0] == "mean" || files[0] == "r" || files[0] == "g" || files[0] == "b") && given["grayscale"] && !fileExists(files[0]) {
+		fail(fmt.Errorf("write -grayscale=%s; a bare -grayscale means mean", files[0]))
+	}
+	if len(files) > 0 && (*inPath != "" || *glob != "" || *fromStdin) {
+		fail(errors.New("give images either as arguments or with -i, -glob, or -stdin, not both"))
+	}
+	// badListed counts -from-file entries left out as missing or not
+	// images; they fail the run like images that can't be rendered.
+	badListed := 0
+	if *fromFile != "" {
+		if len(files) > 0 || *inPath != "" || *glob != "" || *fromStdin {
+			fail(errors.New("-from-file cannot be combined with image arguments, -i, -glob, or -stdin"))
+		}
+		var err error
+		if files, badListed, err = readManifest(*fromFile); err != nil {
+			fail(fmt.Errorf("-from-file: %w", err))
+		}
+		if len(files) == 0 {
+			fail(fmt.Errorf("-from-file: no images listed in %s", *fromFile))
+		}
+	}
+	if len(files) == 1 && *fromFile == "" && (isDir(files[0]) || !*batch && !*montageSheet) {
+		*inPath, files = files[0], nil
+	}
+
+	if *quiet {
+		*interactive = false
+	}
+	if given["w"] && *width <= 0 {
+		fail(errors.New("-w must be > 0"))
+	}
+	if given["scale"] && *scale <= 0 {
+		fail(errors.New("-scale must be > 0"))
+	}
+	if given["h"] && *height <= 0 {
+		fail(errors.New("-h must be > 0"))
+	}
+	if *maxWidth < 0 {
+		fail(errors.New("-max-width must be >= 0"))
+	}
+	switch {
+	case flagSet(flags, "w"):
+		// Given, or taken from the config file.
+	case *height > 0:
+		// Derive the width from -h and the image's aspect ratio.
+		*width = 0
+	case *montageSheet:
+		*width = montageWidth
+	case *outPath == "" && !*batch && *serveAddr == "":
+		if cols, _, ok := terminalSize(os.Stdout); ok {
+			*width = cols
+		}
+	}
+	if *aspect <= 0 {
+		fail(errors.New("-aspect must be > 0"))
+	}
+	if utf8.RuneCountInString(*chars) < 2 {
+		fail(errors.New("-chars must contain at least 2 characters"))
+	}
+	if *ditherSize != 2 && *ditherSize != 4 && *ditherSize != 8 {
+		fail(errors.New("-dither-size must be 2, 4, or 8"))
+	}
+	if *levels < 0 || *levels == 1 {
+		fail(errors.New("-levels must be 0 or at least 2"))
+	}
+	if *calibrateSave && *calibrate == "" {
+		fail(errors.New("-calibrate-save needs -calibrate"))
+	}
+	if *calibrate != "" {
+		glyphs := printableASCII()
+		if given["chars"] {
+			glyphs = []rune(*chars)
+		}
+		saveTo := ""
+		if *calibrateSave {
+			if *configFile == "" {
+				fail(errors.New("-calibrate-save needs a -config file"))
+			}
+			saveTo = *configFile
+		}
+		if err := printCalibration(*calibrate, glyphs, *levels, saveTo, *quiet); err != nil {
+			fail(fmt.Errorf("-calibrate: %w", err))
+		}
+		return
+	}
+	if *gamma <= 0 {
+		fail(errors.New("-gamma must be > 0"))
+	}
+	if *contrast <= 0 {
+		fail(errors.New("-contrast must be > 0"))
+	}
+	if *brightness < -255 || *brightness > 255 {
+		fail(errors.New("-brightness must be between -255 and 255"))
+	}
+	if *blur < 0 {
+		fail(errors.New("-blur must be >= 0"))
+	}
+	if *sharpenAmount < 0 {
+		fail(errors.New("-sharpen must be >= 0"))
+	}
+	if *autoContrastClip < 0 || *autoContrastClip > 50 {
+		fail(errors.New("-autocontrast-clip must be between 0 and 50"))
+	}
+	if *jobs < 0 {
+		fail(errors.New("-jobs must be >= 0"))
+	}
+	if utf8.RuneCountInString(*bwChars) != 2 {
+		fail(errors.New("-bw-chars must contain exactly 2 characters"))
+	}
+	if *bw && (*braille || *halfblock) {
+		fail(errors.New("-bw cannot be combined with -braille or -halfblock"))
+	}
+	if *edges && (*braille || *halfblock || *bw) {
+		fail(errors.New("-edges cannot be combined with -braille, -halfblock, or -bw"))
+	}
+	if *edgeThreshold < 0 {
+		fail(errors.New("-edge-threshold must be >= 0"))
+	}
+	if *threshold < 0 || *threshold > 255 {
+		fail(errors.New("-threshold must be between 0 and 255"))
+	}
+	if *alphaThreshold < 0 || *alphaThreshold > 255 {
+		fail(errors.New("-alpha-threshold must be between 0 and 255"))
+	}
+	switch *format {
+	case "text", "html", "svg", "json", "png", "pgm":
+	default:
+		fail(fmt.Errorf("unknown -format %q (want text, html, svg, json, png, or pgm)", *format))
+	}
+	if *allFrames && (*format == "svg" || *format == "json" || *format == "png" || *format == "pgm") {
+		fail(fmt.Errorf("-all-frames cannot be combined with -format %s", *format))
+	}
+	if *play && (*format != "text" || *outPath != "") {
+		fail(errors.New("-play only writes text to the terminal; drop -format and -o"))
+	}
+	if *batch && (*play || *outPath != "" || *fromStdin) {
+		fail(errors.New("-batch writes one file per image; drop -play, -o, and -stdin"))
+	}
+	if *inPath2 != "" && (*batch || *play || *allFrames) {
+		fail(errors.New("-i2 cannot be combined with -batch, -play, or -all-frames"))
+	}
+	if *montageSheet && (*batch || *play || *allFrames || *inPath2 != "") {
+		fail(errors.New("-montage cannot be combined with -batch, -play, -all-frames, or -i2"))
+	}
+	if *serveAddr != "" && (*batch || *montageSheet || *play || *list || *inPath2 != "" || *outPath != "") {
+		fail(errors.New("-serve cannot be combined with -batch, -montage, -play, -list, -i2, or -o"))
+	}
+	if *serveAddr != "" && *format != "text" && *format != "html" {
+		fail(errors.New("-serve only answers with -format text or html"))
+	}
+	if *watch && (*batch || *montageSheet || *play || *page || *list || *serveAddr != "" || *outPath != "" || *format != "text") {
+		fail(errors.New("-watch only renders text to the terminal; drop -batch, -montage, -play, -page, -list, -serve, -o, and -format"))
+	}
+	if *showFormat && (*batch || *montageSheet || *serveAddr != "" || *watch || *play || *list) {
+		fail(errors.New("-show-format cannot be combined with -batch, -montage, -serve, -watch, -play, or -list"))
+	}
+	if *doubleWidth && *serveAddr != "" {
+		fail(errors.New("-double-width cannot be combined with -serve"))
+	}
+	if *showStats && (*serveAddr != "" || *watch || *play) {
+		fail(errors.New("-stats cannot be combined with -serve, -watch, or -play"))
+	}
+	var inlineProto string
+	if *inline {
+		if *batch || *montageSheet || *serveAddr != "" || *watch || *play || *page || *list || *showFormat || *showStats || *inPath2 != "" || *outPath != "" || *clip || *format != "text" || len(files) > 0 {
+			fail(errors.New("-inline shows one image in the terminal; drop -batch, -montage, -serve, -watch, -play, -page, -list, -show-format, -stats, -i2, -o, -clip, -format, and extra images"))
+		}
+		var err error
+		if inlineProto, err = inlineProtocol(); err != nil {
+			fail(err)
+		}
+	}
+	if *watchInterval <= 0 {
+		fail(errors.New("-watch-interval must be > 0"))
+	}
+	if len(files) > 0 && !*batch && !*montageSheet {
+		if *play || *page || *watch || *inPath2 != "" || *serveAddr != "" {
+			fail(errors.New("several image arguments cannot be combined with -play, -page, -watch, -i2, or -serve"))
+		}
+		if *format != "text" {
+			fail(fmt.Errorf("several images can't share one -format %s output; add -batch to write a file for each", *format))
+		}
+	}
+	if *clip && (*outPath != "" || *batch || *play || *page || *watch || *serveAddr != "" || *list || *format == "png" || *format == "pgm") {
+		fail(errors.New("-clip cannot be combined with -o, -batch, -play, -page, -watch, -serve, -list, or -format png or pgm"))
+	}
+	if *format == "pgm" && (*braille || *halfblock || *pixel || *edges || *montageSheet || *inPath2 != "" || *sparkline || *doubleWidth) {
+		fail(errors.New("-format pgm writes the character ramp's luminance grid; drop -braille, -halfblock, -pixel, -edges, -montage, -i2, -sparkline, and -double-width"))
+	}
+	if *sparkline && (*inPath2 != "" || *serveAddr != "") {
+		fail(errors.New("-sparkline cannot be combined with -i2 or -serve"))
+	}
+	if *cols <= 0 {
+		fail(errors.New("-cols must be > 0"))
+	}
+	if *outDir != "" && !*batch {
+		fail(errors.New("-outdir requires -batch"))
+	}
+	var cropRect image.Rectangle
+	if *crop != "" {
+		r, err := parseCrop(*crop)
+		if err != nil {
+			fail(fmt.Errorf("-crop: %w", err))
+		}
+		cropRect = r
+	}
+	if _, ok := rotateOrientation[*rotate]; !ok {
+		fail(fmt.Errorf("-rotate must be 0, 90, 180, or 270, got %d", *rotate))
+	}
+	if *flipY && (*braille || *halfblock || *pixel || *edges) {
+		fail(errors.New("-flip-y reorders rows of the character ramp; use -flip v with -braille, -halfblock, -pixel, or -edges"))
+	}
+	if _, ok := flipOrientation[*flip]; !ok {
+		fail(fmt.Errorf("unknown -flip %q (want h, v, or hv)", *flip))
+	}
+	if _, ok := borderStyles[*borderStyle]; !ok {
+		fail(fmt.Errorf("unknown -border-style %q (want single, double, rounded, or ascii)", *borderStyle))
+	}
+	if *border && *format != "text" {
+		fail(errors.New("-border only applies to -format text"))
+	}
+	if *frame < 0 {
+		fail(errors.New("-frame must be >= 0"))
+	}
+	if *trimTolerance < 0 || *trimTolerance > 255 {
+		fail(errors.New("-trim-tolerance must be between 0 and 255"))
+	}
+	if *cellSize <= 0 {
+		fail(errors.New("-cell-size must be > 0"))
+	}
+	if *svgBG != "" {
+		if _, err := parseHexColor(*svgBG); err != nil {
+			fail(fmt.Errorf("-svg-bg: %w", err))
+		}
+	}
+	pngBGColor, err := parseHexColor(*pngBG)
+	if err != nil {
+		fail(fmt.Errorf("-png-bg: %w", err))
+	}
+	bgColor, err := parseColor(*bg)
+	if err != nil {
+		fail(fmt.Errorf("-bg: %w", err))
+	}
+	sampling, err := parseSampleMode(*sample)
 	if err != nil {
 		fail(err)
 	}
-	if imgPath == "" {
-		fail(errors.New("no image selected"))
+	if *supersample < 1 {
+		fail(errors.New("-supersample must be >= 1"))
+	}
+	if *supersample > 1 && (sampling != asciiart.SampleNearest || *braille || *halfblock || *pixel || *edges) {
+		fail(errors.New("-supersample only refines -sample nearest for the character ramp; drop -sample, -braille, -halfblock, -pixel, and -edges"))
+	}
+	var weights [3]float64
+	if given["lum-weights"] && grayscale != "" {
+		fail(errors.New("-grayscale and -lum-weights are mutually exclusive"))
+	}
+	if given["lum-weights"] {
+		if weights, err = parseLumWeights(*lumWeights); err != nil {
+			fail(fmt.Errorf("-lum-weights: %w", err))
+		}
+	}
+	var tonePoints []asciiart.TonePoint
+	if *tone != "" {
+		if tonePoints, err = parseTone(*tone); err != nil {
+			fail(fmt.Errorf("-tone: %w", err))
+		}
+	}
+	if *truecolor && *use256 || *truecolor && *use16 || *use256 && *use16 {
+		fail(errors.New("-color, -color256, and -color16 are mutually exclusive"))
+	}
+	mode := colorNone
+	switch {
+	case *truecolor:
+		mode = colorTrue
+	case *use256:
+		mode = color256
+	case *use16:
+		mode = color16
+	}
+	if *halfblock && mode == colorNone {
+		fail(errors.New("-halfblock requires -color, -color256, or -color16"))
+	}
+	if *halfblock && *braille {
+		fail(errors.New("-halfblock and -braille are mutually exclusive"))
+	}
+	if *pixel && mode == colorNone {
+		fail(errors.New("-pixel requires -color, -color256, or -color16"))
+	}
+	if *pixel && (*braille || *halfblock || *edges || *bw) {
+		fail(errors.New("-pixel cannot be combined with -braille, -halfblock, -edges, or -bw"))
+	}
+	if *perceptual && (grayscale != "" || *alphaMask) {
+		fail(errors.New("-perceptual cannot be combined with -grayscale or -alpha"))
+	}
+	if *alphaMask && (grayscale != "" || given["lum-weights"]) {
+		fail(errors.New("-alpha cannot be combined with -grayscale or -lum-weights"))
+	}
+	if *alphaMask && (mode != colorNone || *halfblock || *pixel) {
+		fail(errors.New("-alpha draws the mask without color; drop -color, -color256, -color16, -halfblock, and -pixel"))
+	}
+	if *shade && (mode != colorNone || *braille || *halfblock || *pixel || *edges) {
+		fail(errors.New("-shade is for the plain character ramp; drop -color, -color256, -color16, -braille, -halfblock, -pixel, and -edges"))
+	}
+	if *shade && *format != "text" {
+		fail(errors.New("-shade only applies to -format text"))
+	}
+	// Like ls --color=auto, don't leave escapes in piped or redirected text,
+	// nor in the clipboard, since most apps it's pasted into show them raw.
+	// Half blocks and -pixel blocks are meaningless without color, so they
+	// keep it.
+	if !*forceColor && (*clip || *outPath == "" && !*batch && *serveAddr == "" && !isTerminal(os.Stdout)) {
+		if mode != colorNone && *format == "text" && !*halfblock && !*pixel {
+			mode = colorNone
+		}
+		*shade = false
 	}
 
-	f, err := os.Open(imgPath)
-	if err != nil {
-		fail(fmt.Errorf("open: %w", err))
+	if *autoInvert {
+		if tbg, ok := terminalBackground(); ok {
+			// Dense glyphs stand for dark pixels, which only reads right as
+			// dark ink on a light background.
+			*invert = asciiart.Luminance8(uint32(tbg.R)*0x101, uint32(tbg.G)*0x101, uint32(tbg.B)*0x101) < 128
+		}
 	}
-	defer f.Close()
 
-	img, _, err := image.Decode(f)
-	if err != nil {
-		fail(fmt.Errorf("decode: %w", err))
+	// Characters are taller than wide; -aspect tunes this per font.
+	charAspect := *aspect
+	opts := asciiart.Options{
+		Width:      *width,
+		Height:     *height,
+		CharAspect: charAspect,
+		Invert:     *invert,
+		Charset:    *chars,
+		Gamma:      *gamma,
+		Tone:       tonePoints,
+		Contrast:   *contrast,
+		Brightness: *brightness,
+		Sample:     sampling,
+		Dither:     dither == "fs",
+		BW:         *bw,
+		BWChars:    *bwChars,
+		Threshold:  uint8(*threshold),
+		Jobs:       *jobs,
+		LumWeights: weights,
+		Gray:       grayscale.mode(),
+		Background: bgColor,
+
+		TransparentSpace: *transparentSpace,
+		AlphaThreshold:   uint8(*alphaThreshold),
+		Supersample:      *supersample,
+		Perceptual:       *perceptual,
+		Blur:             *blur,
+		Sharpen:          *sharpenAmount,
+		AutoContrast:     *autoContrast,
+		AutoContrastClip: *autoContrastClip,
+		Equalize:         *equalize,
+		FlipY:            *flipY,
+		Levels:           *levels,
+		EdgeThreshold:    *edgeThreshold,
+	}
+	if dither == "ordered" {
+		opts.Bayer = *ditherSize
+	}
+	if *alphaMask {
+		opts.Gray = asciiart.GrayAlpha
+	}
+	// stats collects the -stats report on the image being rendered; it is
+	// nil without -stats.
+	var stats *renderStats
+	if *showStats {
+		stats = &renderStats{}
+		opts.Timings = &stats.timings
+	}
+	// termCols is the terminal width text output is checked against, or 0
+	// when it isn't going to a terminal or the check is off.
+	termCols := 0
+	if *format == "text" && *outPath == "" && !*clip && *serveAddr == "" && !*batch && !*noWrapWarn && !*quiet {
+		if cols, _, ok := terminalSize(os.Stdout); ok {
+			termCols = cols
+		}
+	}
+	// warnWrap warns, once, that rows cols wide will wrap in the terminal.
+	// The output itself is left as it is.
+	warnWrap := func(cols int) {
+		if termCols == 0 || cols <= termCols {
+			return
+		}
+		hint := "a smaller -w"
+		if *pixel {
+			hint = "a smaller image or -crop, since -pixel draws a column per source pixel"
+		}
+		fmt.Fprintf(os.Stderr, "warning: the output is %d columns wide, wider than the %d-column terminal, so lines will wrap; try %s (-no-wrap-warn hides this)\n", cols, termCols, hint)
+		termCols = 0
+	}
+	// renderMode renders img with opts in the mode chosen by the flags.
+	renderMode := func(img image.Image, opts asciiart.Options) (asciiart.Grid, error) {
+		switch {
+		case *braille:
+			return asciiart.RenderBraille(img, opts)
+		case *halfblock:
+			return asciiart.RenderHalfBlock(img, opts)
+		case *pixel:
+			b := img.Bounds()
+			cols := b.Dx()
+			if *doubleWidth {
+				cols *= 2
+			}
+			if *maxWidth > 0 && max(cols, b.Dy()) > *maxWidth {
+				return nil, fmt.Errorf("-pixel draws a character per pixel, and the %dx%d image exceeds -max-width %d", b.Dx(), b.Dy(), *maxWidth)
+			}
+			return asciiart.RenderPixels(img, opts)
+		case *edges:
+			return asciiart.RenderEdges(img, opts)
+		default:
+			return asciiart.RenderGrid(img, opts)
+		}
+	}
+	if *serveAddr != "" {
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "serving on %s\n", *serveAddr)
+		}
+		fail(serve(*serveAddr, &renderServer{
+			opts:       opts,
+			render:     renderMode,
+			format:     *format,
+			colored:    mode != colorNone,
+			charAspect: charAspect,
+			cellSize:   *cellSize,
+		}))
+	}
+	// beside is the -i2 image, drawn to the right of every rendered image.
+	var beside image.Image
+	warnedCap := false
+	// imageOpts returns opts sized for img, within -max-width.
+	imageOpts := func(img image.Image) asciiart.Options {
+		opts := opts
+		if *scale > 0 {
+			// One character per 1/scale source pixels, whatever -w says.
+			opts.Width = max(1, int(math.Round(float64(img.Bounds().Dx())**scale)))
+		}
+		if *doubleWidth {
+			// Each sample is drawn twice, so a cell is twice as wide.
+			if opts.Width > 0 {
+				opts.Width = max(1, opts.Width/2)
+			}
+			opts.CharAspect *= 2
+		}
+		if *maxWidth > 0 && !*pixel {
+			// -max-width counts output columns, which -double-width makes
+			// two of each sampled one.
+			perCol := 1
+			if *doubleWidth {
+				perCol = 2
+			}
+			var cols, rows, newCols, newRows int
+			opts, cols, rows, newCols, newRows = capGrid(img, opts, max(1, *maxWidth/perCol), *maxWidth)
+			if (cols != newCols || rows != newRows) && !warnedCap && !*quiet {
+				fmt.Fprintf(os.Stderr, "warning: a %dx%d character grid exceeds -max-width %d; rendering %dx%d instead\n", cols*perCol, rows, *maxWidth, newCols*perCol, newRows)
+				warnedCap = true
+			}
+		}
+		return opts
+	}
+	render := func(img image.Image) (asciiart.Grid, error) {
+		if stats != nil {
+			defer func(start time.Time) { stats.render += time.Since(start) }(time.Now())
+		}
+		opts := imageOpts(img)
+		g, err := renderMode(img, opts)
+		if err == nil && *sparkline {
+			g, err = addSparklines(img, g, opts)
+		}
+		if err == nil && beside != nil {
+			// Match the first image's height; the width follows from the
+			// second image's own aspect ratio.
+			opts.Width, opts.Height = 0, len(g)
+			var g2 asciiart.Grid
+			if g2, err = renderMode(beside, opts); err == nil {
+				g = sideBySide(g, g2)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("render: %w", err)
+		}
+		if *doubleWidth {
+			g = doubleCells(g)
+		}
+		if stats != nil && len(g) > 0 {
+			stats.cols, stats.rows = len(g[0]), len(g)
+		}
+		return g, nil
+	}
+	// textRows encodes cells as lines of text, framed when -border is set.
+	// shadeRamp is the ramp -shade reads each glyph's brightness from, in
+	// the order glyphs were picked.
+	shadeRamp := []rune(opts.Charset)
+	if opts.BW {
+		shadeRamp = []rune(opts.BWChars)
+	}
+	if opts.Invert {
+		slices.Reverse(shadeRamp)
+	}
+	textRows := func(cells asciiart.Grid) []string {
+		var rows []string
+		if *shade {
+			rows = shadeRows(cells, shadeRamp)
+		} else {
+			rows = ansiRows(cells, mode)
+		}
+		if *pad {
+			rows = padRows(rows)
+		}
+		if *border {
+			rows = addBorder(rows, borderStyles[*borderStyle])
+		}
+		return rows
+	}
+	// svgSize picks the raster size of an SVG from its intrinsic size:
+	// -cell-size pixels per output column, or the intrinsic size itself
+	// under -scale, which counts source pixels.
+	svgSize := func(w, h float64) (int, int, error) {
+		if *scale > 0 {
+			return svgPixels(w, h)
+		}
+		return svgRasterSize(w, h, opts, *cellSize)
+	}
+	// loadFrames decodes the image at p and picks the frames to render.
+	loadFrames := func(p string) (animation, []image.Image, error) {
+		f, err := openInput(p)
+		if err != nil {
+			return animation{}, nil, fmt.Errorf("open: %w", err)
+		}
+		defer f.Close()
+
+		var in io.Reader = f
+		if stats != nil {
+			in = &countingReader{r: f}
+		}
+		start := time.Now()
+		page := *frame
+		if *allFrames || *play {
+			page = -1
+		}
+		anim, err := decodeAnimation(in, decodeOptions{autorotate: !*noAutorotate, svgSize: svgSize, page: page})
+		if err != nil {
+			var de *decodeError
+			var pe *pixelLimitError
+			if !errors.As(err, &de) && !errors.As(err, &pe) {
+				return animation{}, nil, fmt.Errorf("read: %w", err)
+			}
+			return animation{}, nil, fmt.Errorf("decode: %w", err)
+		}
+		if stats != nil {
+			// Each input starts a fresh report.
+			b := anim.frames[0].Bounds()
+			*stats = renderStats{
+				format: anim.format,
+				bytes:  in.(*countingReader).n,
+				frames: len(anim.frames),
+				srcW:   b.Dx(),
+				srcH:   b.Dy(),
+				decode: time.Since(start),
+			}
+		}
+		frames := anim.frames
+		if !*allFrames && !*play {
+			if *frame >= len(frames) {
+				return animation{}, nil, fmt.Errorf("-frame %d out of range (image has %d frames)", *frame, len(frames))
+			}
+			frames = frames[*frame : *frame+1]
+		}
+		if *crop != "" {
+			cropped := make([]image.Image, len(frames))
+			for i, img := range frames {
+				if cropped[i], err = cropImage(img, cropRect); err != nil {
+					return animation{}, nil, fmt.Errorf("-crop: %w", err)
+				}
+			}
+			frames = cropped
+		}
+		if *rotate != 0 || *flip != "" {
+			turned := make([]image.Image, len(frames))
+			for i, img := range frames {
+				turned[i] = rotateFlip(img, *rotate, *flip)
+			}
+			frames = turned
+		}
+		if *trim {
+			frames = trimFrames(frames, *trimTolerance)
+		}
+		return anim, frames, nil
+	}
+	// writeGrid writes cells to out in the chosen -format.
+	writeGrid := func(out *bufio.Writer, cells asciiart.Grid) error {
+		switch *format {
+		case "html":
+			writeHTML(out, cells, mode != colorNone, charAspect)
+		case "svg":
+			writeSVG(out, cells, svgOptions{
+				colored: mode != colorNone,
+				cellW:   *cellSize,
+				cellH:   *cellSize / charAspect,
+				bg:      *svgBG,
+			})
+		case "json":
+			return writeJSON(out, cells, mode != colorNone)
+		case "png":
+			return writePNG(out, cells, pngOptions{
+				colored: mode != colorNone,
+				cellW:   max(1, int(math.Round(*cellSize))),
+				cellH:   max(1, int(math.Round(*cellSize/charAspect))),
+				bg:      pngBGColor,
+			})
+		default:
+			rows := textRows(cells)
+			if termCols > 0 {
+				cols := 0
+				for _, row := range rows {
+					cols = max(cols, visibleWidth(row))
+				}
+				warnWrap(cols)
+			}
+			writeRows(out, rows)
+		}
+		return nil
+	}
+	// Plain text from the glyph ramp needs no grid, so it is written row by
+	// row as it is rendered.
+	// -stats reads the grid size off the grid.
+	streamText := *format == "text" && mode == colorNone && !*border && !*braille && !*halfblock && !*pixel && !*edges && !*sparkline && !*doubleWidth && !*shade && stats == nil
+	// writeFrames renders frames to out in the chosen -format.
+	writeFrames := func(out *bufio.Writer, frames []image.Image) error {
+		for i, img := range frames {
+			if *format == "pgm" {
+				lum, err := asciiart.LuminanceGrid(img, imageOpts(img))
+				if err != nil {
+					return fmt.Errorf("render: %w", err)
+				}
+				if stats != nil && len(lum) > 0 {
+					stats.cols, stats.rows = len(lum[0]), len(lum)
+				}
+				if err := writePGM(out, lum, opts.Invert); err != nil {
+					return err
+				}
+				continue
+			}
+			if streamText && beside == nil {
+				if i > 0 {
+					out.WriteByte('\f')
+				}
+				opts := imageOpts(img)
+				if termCols > 0 {
+					_, _, _, cols, _ := capGrid(img, opts, math.MaxInt32, math.MaxInt32)
+					warnWrap(cols)
+				}
+				if err := asciiart.RenderTo(img, out, opts); err != nil {
+					return fmt.Errorf("render: %w", err)
+				}
+				continue
+			}
+			cells, err := render(img)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				out.WriteByte('\f')
+			}
+			start := time.Now()
+			if err := writeGrid(out, cells); err != nil {
+				return err
+			}
+			if stats != nil {
+				out.Flush()
+				stats.write += time.Since(start)
+			}
+		}
+		return nil
+	}
+	// openOutput creates the -o file, or the -clip buffer, or returns stdout
+	// when neither is set.
+	openOutput := func() io.WriteCloser {
+		if *clip {
+			return &clipboardWriter{}
+		}
+		if (*format == "png" || *format == "pgm") && *outPath == "" && isTerminal(os.Stdout) {
+			fail(fmt.Errorf("-format %s writes binary data; use -o or redirect stdout", *format))
+		}
+		if *outPath == "" {
+			return os.Stdout
+		}
+		of, err := os.Create(*outPath)
+		if err != nil {
+			fail(fmt.Errorf("create output: %w", err))
+		}
+		return of
+	}
+	// closeOutput closes the output from openOutput, which for -clip copies
+	// it to the clipboard.
+	closeOutput := func(dst io.WriteCloser) {
+		if err := dst.Close(); err != nil {
+			fail(err)
+		}
+		if _, ok := dst.(*clipboardWriter); ok && !*quiet {
+			fmt.Fprintln(os.Stderr, "copied to the clipboard")
+		}
 	}
 
-	w := img.Bounds().Dx()
-	h := img.Bounds().Dy()
-	if w == 0 || h == 0 {
-		fail(errors.New("image has zero dimension"))
+	// dirImages returns the images given as arguments, or else those in the
+	// -i directory, and the directory they all lie under.
+	dirImages := func(mode string) (root string, paths []string) {
+		if len(files) > 0 {
+			return commonDir(files), files
+		}
+		if !isDir(*inPath) {
+			fail(fmt.Errorf("%s requires -i to name a directory, or image arguments", mode))
+		}
+		return *inPath, filterByGlob(listImages(*inPath, *recursive), *glob)
 	}
 
-	// Adjust height to account for character aspect ratio (chars are taller than wide).
-	charAspect := 0.5 // tweak to taste (smaller = fewer rows)
-	newW := *width
-	newH := int(math.Max(1, math.Round(float64(h)*charAspect*float64(newW)/float64(w))))
+	if *batch {
+		root, paths := dirImages("-batch")
+		if *list {
+			for _, p := range paths {
+				fmt.Println(p)
+			}
+			return
+		}
+		ok, failed := runBatch(root, paths, *outDir, formatExt(*format), func(out *bufio.Writer, p string) error {
+			_, frames, err := loadFrames(p)
+			if err != nil {
+				return err
+			}
+			if err := writeFrames(out, frames); err != nil {
+				return err
+			}
+			if stats != nil {
+				stats.print(os.Stderr, p)
+			}
+			return nil
+		})
+		failed += badListed
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "batch: %d succeeded, %d failed\n", ok, failed)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
 
-	ascii := renderASCII(img, newW, newH, *invert)
+	if *montageSheet {
+		root, paths := dirImages("-montage")
+		if *list {
+			for _, p := range paths {
+				fmt.Println(p)
+			}
+			return
+		}
+		var thumbs []asciiart.Grid
+		var labels []string
+		for _, p := range paths {
+			_, frames, err := loadFrames(p)
+			var g asciiart.Grid
+			if err == nil {
+				g, err = render(frames[0])
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s: %v\n", p, err)
+				continue
+			}
+			label, err := filepath.Rel(absPath(root), absPath(p))
+			if err != nil {
+				label = filepath.Base(p)
+			}
+			thumbs = append(thumbs, g)
+			labels = append(labels, label)
+		}
+		if len(thumbs) == 0 {
+			fail(errors.New("-montage: no images could be rendered"))
+		}
+		dst := openOutput()
+		out := bufio.NewWriter(dst)
+		if err := writeGrid(out, montage(thumbs, labels, *cols)); err != nil {
+			fail(err)
+		}
+		out.Flush()
+		closeOutput(dst)
+		return
+	}
+
+	if len(files) > 0 {
+		if *list {
+			for _, p := range files {
+				fmt.Println(p)
+			}
+			return
+		}
+		if *showFormat {
+			failed := badListed
+			for _, p := range files {
+				anim, _, err := loadFrames(p)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "error: %s: %v\n", p, err)
+					failed++
+					continue
+				}
+				fmt.Println(formatLine(p, anim.format))
+			}
+			if failed > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+		dst := openOutput()
+		out := bufio.NewWriter(dst)
+		// Each image gets its name as a header, with a blank line before
+		// every header but the first.
+		failed, wrote := badListed, false
+		for _, p := range files {
+			_, frames, err := loadFrames(p)
+			if err == nil {
+				if wrote {
+					out.WriteByte('\n')
+				}
+				fmt.Fprintln(out, p)
+				wrote = true
+				err = writeFrames(out, frames)
+			}
+			if err == nil && stats != nil {
+				stats.print(os.Stderr, p)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s: %v\n", p, err)
+				failed++
+			}
+		}
+		out.Flush()
+		closeOutput(dst)
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *list {
+		cands, choose, err := findCandidates(*inPath, *glob, *fromStdin, *recursive)
+		if err != nil {
+			fail(err)
+		}
+		if !choose || !*interactive {
+			cands = cands[:1]
+		}
+		def := 0
+		if choose && *interactive && isTerminal(os.Stdin) {
+			def, _ = lastChoiceIndex(cands)
+		}
+		writeCandidates(os.Stdout, cands, def, *interactive)
+		return
+	}
+
+	// Resolve which image to open.
+	imgPath, err := resolveInput(*inPath, *glob, *fromStdin, *interactive, *strict, *recursive)
+	if err != nil {
+		fail(err)
+	}
+	if imgPath == "" {
+		fail(errors.New("no image selected"))
+	}
+	if *inPath2 != "" {
+		_, frames2, err := loadFrames(*inPath2)
+		if err != nil {
+			fail(fmt.Errorf("-i2: %w", err))
+		}
+		beside = frames2[0]
+	}
+	if *watch {
+		if isURL(imgPath) || isSpecialFile(imgPath) {
+			fail(errors.New("-watch needs a regular file, not a URL or pipe"))
+		}
+		out := bufio.NewWriter(os.Stdout)
+		stop := notifyStop()
+		load := func(p string) ([]image.Image, error) {
+			_, frames, err := loadFrames(p)
+			return frames, err
+		}
+		watchFile(imgPath, *watchInterval, stop, load, func(frames []image.Image, err error) {
+			out.WriteString("\x1b[H\x1b[2J")
+			if err == nil {
+				err = writeFrames(out, frames)
+			}
+			if err != nil {
+				// Often a save caught halfway; the next one re-renders.
+				fmt.Fprintf(out, "error: %v\n", err)
+			}
+			out.Flush()
+		})
+		out.WriteString(restoreTerminal)
+		out.Flush()
+		return
+	}
+	if *inline {
+		data, err := readInput(imgPath)
+		if err != nil {
+			fail(err)
+		}
+		out := bufio.NewWriter(os.Stdout)
+		if err := writeInline(out, data, sniffFormat(data), inlineProto, *width, *height); err != nil {
+			fail(err)
+		}
+		out.Flush()
+		return
+	}
+	anim, frames, err := loadFrames(imgPath)
+	if err != nil {
+		fail(err)
+	}
+	if *showFormat {
+		fmt.Println(formatLine(imgPath, anim.format))
+		return
+	}
+	if stats != nil {
+		// Deferred first, so it runs after the output is flushed.
+		defer stats.print(os.Stderr, imgPath)
+	}
 
-	out := bufio.NewWriter(os.Stdout)
-	defer out.Flush()
-	for _, row := range ascii {
-		out.WriteString(row)
-		out.WriteByte('\n')
+	dst := openOutput()
+	out := bufio.NewWriter(dst)
+	defer func() {
+		out.Flush()
+		closeOutput(dst)
+	}()
+	if *play {
+		texts := make([][]string, len(frames))
+		for i, img := range frames {
+			cells, err := render(img)
+			if err != nil {
+				fail(err)
+			}
+			texts[i] = textRows(cells)
+		}
+		playAnimation(out, texts, anim.delays, anim.loopCount, notifyStop())
+		return
+	}
+	if *page && *outPath == "" && *format == "text" && isTerminal(os.Stdout) && isTerminal(os.Stdin) {
+		if _, termRows, ok := terminalSize(os.Stdout); ok {
+			var rows []string
+			for _, img := range frames {
+				cells, err := render(img)
+				if err != nil {
+					fail(err)
+				}
+				rows = append(rows, textRows(cells)...)
+			}
+			// Without key mode, keys only arrive once Enter is pressed.
+			if restore, err := keyMode(os.Stdin); err == nil {
+				defer restore()
+			}
+			pageRows(out, rows, termRows-1, readKeys(os.Stdin), notifyStop())
+			out.WriteString(restoreTerminal)
+			return
+		}
+	}
+	if err := writeFrames(out, frames); err != nil {
+		fail(err)
 	}
 }
 
+// flagSet reports whether the named flag has been set, on the command line
+// or by the preset or config file applied so far.
+func flagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func fail(err error) {
 	fmt.Fprintln(os.Stderr, "error:", err)
 	os.Exit(1)
 }
 
+// inputEnv names the environment variable used as -i when no input flag is
+// given.
+const inputEnv = "IMGTOASCII_INPUT"
+
 // resolveInput determines which image file to use based on flags and environment.
-func resolveInput(inPath, glob string, fromStdin, interactive bool) (string, error) {
+func resolveInput(inPath, glob string, fromStdin, interactive, strict, recursive bool) (string, error) {
+	cands, choose, err := findCandidates(inPath, glob, fromStdin, recursive)
+	if err != nil {
+		return "", err
+	}
+	if choose && interactive {
+		return pickInteractive(cands, strict)
+	}
+	return cands[0], nil
+}
+
+// findCandidates runs the input resolution without prompting. It returns the
+// images to choose from, in menu order, and whether they came from a directory
+// or glob (so should be offered as a menu) rather than naming one image. The
+// first candidate is the one picked when not interactive.
+func findCandidates(inPath, glob string, fromStdin, recursive bool) (cands []string, choose bool, err error) {
 	// 1) stdin takes precedence
 	if fromStdin {
 		s := bufio.NewScanner(os.Stdin)
@@ -87,47 +1229,45 @@ func resolveInput(inPath, glob string, fromStdin, interactive bool) (string, err
 			if p == "" {
 				continue
 			}
-			if fileExists(p) && isImageExt(p) {
-				return p, nil
+			if isURL(p) || isImageFile(p) {
+				return []string{p}, false, nil
 			}
 			// If directory, try to pick from it
 			if isDir(p) {
 				cands := imagesInDir(p)
 				if len(cands) > 0 {
-					if interactive {
-						return pickInteractive(cands)
-					}
-					return cands[0], nil
+					return cands, true, nil
 				}
 			}
 			// If file exists but not an image, keep scanning
 		}
 		if err := s.Err(); err != nil {
-			return "", fmt.Errorf("stdin: %w", err)
+			return nil, false, fmt.Errorf("stdin: %w", err)
 		}
-		return "", errors.New("no usable path from stdin")
+		return nil, false, errors.New("no usable path from stdin")
 	}
 
-	// 2) explicit path
+	// 2) explicit path or URL
 	if inPath != "" {
+		if isURL(inPath) {
+			// The decoder detects the format; URLs often lack an extension.
+			return []string{inPath}, false, nil
+		}
 		if isDir(inPath) {
-			cands := imagesInDir(inPath)
+			cands := listImages(inPath, recursive)
 			cands = filterByGlob(cands, glob)
 			if len(cands) == 0 {
-				return "", fmt.Errorf("no images found in directory: %s", inPath)
-			}
-			if interactive {
-				return pickInteractive(cands)
+				return nil, false, fmt.Errorf("no images found in directory: %s", inPath)
 			}
-			return cands[0], nil
+			return cands, true, nil
 		}
 		if fileExists(inPath) {
-			if isImageExt(inPath) {
-				return inPath, nil
+			if isImageFile(inPath) {
+				return []string{inPath}, false, nil
 			}
-			return "", fmt.Errorf("not an image: %s", inPath)
+			return nil, false, fmt.Errorf("not an image: %s", inPath)
 		}
-		return "", fmt.Errorf("path not found: %s", inPath)
+		return nil, false, fmt.Errorf("path not found: %s", inPath)
 	}
 
 	// 3) glob across current directory (non-recursive)
@@ -135,23 +1275,41 @@ func resolveInput(inPath, glob string, fromStdin, interactive bool) (string, err
 		matches, _ := filepath.Glob(glob)
 		cands := filterImages(matches)
 		if len(cands) == 0 {
-			return "", fmt.Errorf("glob matched no images: %s", glob)
+			return nil, false, fmt.Errorf("glob matched no images: %s", glob)
 		}
-		if interactive {
-			return pickInteractive(cands)
+		return cands, true, nil
+	}
+
+	// 4) the environment, under the same rules as -i
+	if env := os.Getenv(inputEnv); env != "" {
+		cands, choose, err := findCandidates(env, "", false, recursive)
+		if err != nil {
+			return nil, false, fmt.Errorf("$%s: %w", inputEnv, err)
 		}
-		return cands[0], nil
+		return cands, choose, nil
 	}
 
-	// 4) interactive from current directory by default
-	cands := imagesInDir(".")
+	// 5) interactive from current directory by default
+	cands = imagesInDir(".")
 	if len(cands) == 0 {
-		return "", errors.New("no images found in current directory; pass -i, --glob, or --stdin")
+		return nil, false, errors.New("no images found in current directory; pass -i, --glob, or --stdin, or set $" + inputEnv)
 	}
-	if interactive {
-		return pickInteractive(cands)
+	return cands, true, nil
+}
+
+// writeCandidates prints one path per line. When marked, every path is
+// indented and the default choice, cands[def], is flagged with "*".
+func writeCandidates(w io.Writer, cands []string, def int, marked bool) {
+	for i, c := range cands {
+		switch {
+		case !marked:
+			fmt.Fprintln(w, c)
+		case i == def:
+			fmt.Fprintln(w, "*", c)
+		default:
+			fmt.Fprintln(w, " ", c)
+		}
 	}
-	return cands[0], nil
 }
 
 func isDir(p string) bool {
@@ -164,16 +1322,61 @@ func fileExists(p string) bool {
 	return err == nil && !st.IsDir()
 }
 
+// isSpecialFile reports whether p is neither a regular file nor a directory
+// but, like a FIFO or the /dev/fd/63 of a shell's process substitution,
+// might still stream an image. Such names rarely carry an extension, so
+// they are opened and left to the decoder instead.
+func isSpecialFile(p string) bool {
+	st, err := os.Stat(p)
+	return err == nil && !st.IsDir() && !st.Mode().IsRegular()
+}
+
+// isImageFile reports whether p is a file to try decoding: one with an image
+// extension, or a special file.
+func isImageFile(p string) bool {
+	return fileExists(p) && (isImageExt(p) || isSpecialFile(p))
+}
+
 func isImageExt(p string) bool {
 	ext := strings.ToLower(filepath.Ext(p))
 	switch ext {
-	case ".png", ".jpg", ".jpeg", ".gif", ".bmp", ".tif", ".tiff":
+	case ".png", ".jpg", ".jpeg", ".gif", ".bmp", ".tif", ".tiff", ".webp", ".svg":
 		return true
 	default:
 		return false
 	}
 }
 
+// listImages returns the images in dir, including those in subdirectories
+// when recursive is set.
+func listImages(dir string, recursive bool) []string {
+	if recursive {
+		return imagesInTree(dir)
+	}
+	return imagesInDir(dir)
+}
+
+// imagesInTree returns the images anywhere under dir, sorted. Subdirectories
+// that can't be read are skipped. Symlinks to directories aren't followed, so
+// a link cycle can't make the walk loop.
+func imagesInTree(dir string) []string {
+	var out []string
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && p != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && isImageExt(p) {
+			out = append(out, p)
+		}
+		return nil
+	})
+	sort.Strings(out)
+	return out
+}
+
 func imagesInDir(dir string) []string {
 	ents, err := os.ReadDir(dir)
 	if err != nil {
@@ -220,33 +1423,70 @@ func filterByGlob(paths []string, glob string) []string {
 	return out
 }
 
-func pickInteractive(cands []string) (string, error) {
+// pickInteractive asks on the terminal which of cands to render, offering the
+// one last picked from the same directory as the default. Typing filters the
+// list by file name (see pickFiltered) where the terminal allows it. Without a terminal
+// on stdin it takes the first, or with strict fails when there is more than
+// one.
+func pickInteractive(cands []string, strict bool) (string, error) {
+	// Nobody can answer a prompt on a pipe or /dev/null, as in cron jobs and
+	// CI; reading it would just see end of input.
+	if !isTerminal(os.Stdin) {
+		if strict && len(cands) > 1 {
+			return "", fmt.Errorf("%d images to choose from but stdin is not a terminal; pass -i with a single image", len(cands))
+		}
+		return cands[0], nil
+	}
+	dir := commonDir(cands)
+	def, remembered := lastChoiceIndex(cands)
 	in := bufio.NewReader(os.Stdin)
+	if restore, err := keyMode(os.Stdin); err == nil {
+		// Ctrl-C still interrupts; put the terminal back before exiting.
+		cancel := exitOnStop(os.Stderr, restore)
+		maxRows := len(cands)
+		if _, rows, ok := terminalSize(os.Stderr); ok {
+			maxRows = max(1, rows-3)
+		}
+		out := bufio.NewWriter(os.Stderr)
+		choice := pickFiltered(in, out, cands, def, remembered, maxRows)
+		cancel()
+		restore()
+		if !isURL(choice) {
+			rememberChoice(dir, absPath(choice))
+		}
+		return choice, nil
+	}
+	// Without key mode, fall back to a numbered list and a line of input.
 	fmt.Fprintln(os.Stderr, "Select an image to render:")
 	for i, c := range cands {
+		if remembered && i == def {
+			fmt.Fprintf(os.Stderr, "  %d) %s (last choice)\n", i+1, c)
+			continue
+		}
 		fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, c)
 	}
-	fmt.Fprintf(os.Stderr, "Enter number (1-%d) or a path (default 1): ", len(cands))
+	fmt.Fprintf(os.Stderr, "Enter number (1-%d) or a path (default %d): ", len(cands), def+1)
 	line, _ := in.ReadString('\n')
 	line = strings.TrimSpace(line)
-	if line == "" {
-		return cands[0], nil
-	}
-	// If numeric
-	if idx, err := parseIndex(line, len(cands)); err == nil {
-		return cands[idx], nil
-	}
-	// Otherwise treat as path
-	if fileExists(line) && isImageExt(line) {
-		return line, nil
-	}
-	if isDir(line) {
-		d := imagesInDir(line)
-		if len(d) > 0 {
-			return d[0], nil
+	idx := def
+	if line != "" {
+		var err error
+		if idx, err = parseIndex(line, len(cands)); err != nil {
+			// Otherwise treat as path
+			if isURL(line) || isImageFile(line) {
+				return line, nil
+			}
+			if isDir(line) {
+				d := imagesInDir(line)
+				if len(d) > 0 {
+					return d[0], nil
+				}
+			}
+			return "", errors.New("invalid selection")
 		}
 	}
-	return "", errors.New("invalid selection")
+	rememberChoice(dir, absPath(cands[idx]))
+	return cands[idx], nil
 }
 
 func parseIndex(s string, n int) (int, error) {
@@ -263,53 +1503,147 @@ func parseIndex(s string, n int) (int, error) {
 	return i, nil
 }
 
-func renderASCII(img image.Image, newW, newH int, invert bool) []string {
-	// From dark to light
-	charset := []rune("@%#*+=-:. ")
-	if invert {
-		// reverse
-		for i, j := 0, len(charset)-1; i < j; i, j = i+1, j-1 {
-			charset[i], charset[j] = charset[j], charset[i]
-		}
+// ditherFlag is the -dither mode: "" for none, "fs", or "ordered". It is a
+// bool flag, so a bare -dither keeps meaning Floyd-Steinberg.
+type ditherFlag string
+
+func (d *ditherFlag) String() string { return string(*d) }
+
+func (d *ditherFlag) IsBoolFlag() bool { return true }
+
+func (d *ditherFlag) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "true", "fs":
+		*d = "fs"
+	case "false":
+		*d = ""
+	case "ordered":
+		*d = "ordered"
+	default:
+		return fmt.Errorf("unknown mode %q (want fs or ordered)", s)
+	}
+	return nil
+}
+
+// grayFlag is the -grayscale mode: "" for the weighted default, "mean", or
+// "r", "g" or "b" for a single channel. Like ditherFlag, it is a bool flag,
+// so a bare -grayscale means the mean.
+type grayFlag string
+
+func (g *grayFlag) String() string { return string(*g) }
+
+func (g *grayFlag) IsBoolFlag() bool { return true }
+
+func (g *grayFlag) Set(s string) error {
+	switch s = strings.ToLower(s); s {
+	case "true", "mean":
+		*g = "mean"
+	case "false":
+		*g = ""
+	case "r", "g", "b":
+		*g = grayFlag(s)
+	default:
+		return fmt.Errorf("unknown mode %q (want mean, r, g, or b)", s)
+	}
+	return nil
+}
+
+// mode returns the asciiart.GrayMode for g.
+func (g grayFlag) mode() asciiart.GrayMode {
+	switch g {
+	case "mean":
+		return asciiart.GrayMean
+	case "r":
+		return asciiart.GrayRed
+	case "g":
+		return asciiart.GrayGreen
+	case "b":
+		return asciiart.GrayBlue
+	default:
+		return asciiart.GrayWeighted
 	}
+}
 
-	origW := img.Bounds().Dx()
-	origH := img.Bounds().Dy()
+// parseSampleMode parses the -sample flag.
+func parseSampleMode(s string) (asciiart.SampleMode, error) {
+	switch strings.ToLower(s) {
+	case "nearest":
+		return asciiart.SampleNearest, nil
+	case "average":
+		return asciiart.SampleAverage, nil
+	case "lanczos":
+		return asciiart.SampleLanczos, nil
+	default:
+		return 0, fmt.Errorf("unknown -sample mode %q (want nearest, average, or lanczos)", s)
+	}
+}
 
-	rows := make([]string, newH)
-	for y := 0; y < newH; y++ {
-		// nearest-neighbor sampling
-		sy := int(float64(y) * float64(origH) / float64(newH))
-		if sy >= origH {
-			sy = origH - 1
+// parseLumWeights parses three comma-separated R,G,B weights and normalizes
+// them to sum to 1.
+func parseLumWeights(s string) ([3]float64, error) {
+	var w [3]float64
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return w, fmt.Errorf("want 3 comma-separated weights, got %q", s)
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return w, fmt.Errorf("invalid weight %q", p)
 		}
-		buf := make([]rune, newW)
-		for x := 0; x < newW; x++ {
-			sx := int(float64(x) * float64(origW) / float64(newW))
-			if sx >= origW {
-				sx = origW - 1
-			}
-			r, g, b, _ := img.At(img.Bounds().Min.X+sx, img.Bounds().Min.Y+sy).RGBA()
-			lum := luminance8(r, g, b) // 0..255
-			idx := int(math.Round(float64(lum) * float64(len(charset)-1) / 255.0))
-			buf[x] = charset[idx]
+		w[i] = v
+	}
+	return asciiart.NormalizeWeights(w)
+}
+
+// parseTone parses semicolon-separated in,out points of a tone curve. The
+// inputs must rise strictly within 0..255; outputs are clamped to 0..255
+// when the curve is built.
+func parseTone(s string) ([]asciiart.TonePoint, error) {
+	var pts []asciiart.TonePoint
+	for _, p := range strings.Split(s, ";") {
+		in, out, ok := strings.Cut(p, ",")
+		if !ok {
+			return nil, fmt.Errorf("want in,out, got %q", p)
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(in), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", in)
+		}
+		y, err := strconv.ParseFloat(strings.TrimSpace(out), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", out)
+		}
+		if x < 0 || x > 255 {
+			return nil, fmt.Errorf("input %g is outside 0..255", x)
+		}
+		if len(pts) > 0 && x <= pts[len(pts)-1].In {
+			return nil, fmt.Errorf("inputs must increase, but %g follows %g", x, pts[len(pts)-1].In)
 		}
-		rows[y] = string(buf)
+		pts = append(pts, asciiart.TonePoint{In: x, Out: y})
 	}
-	return rows
+	if len(pts) < 2 {
+		return nil, errors.New("want at least 2 points")
+	}
+	return pts, nil
 }
 
-func luminance8(r, g, b uint32) uint8 {
-	// Convert 16-bit per channel to 8-bit and compute luma.
-	r8 := float64(r >> 8)
-	g8 := float64(g >> 8)
-	b8 := float64(b >> 8)
-	// Rec. 709 luma approximation
-	l := 0.2126*r8 + 0.7152*g8 + 0.0722*b8
-	if l < 0 {
-		l = 0
-	} else if l > 255 {
-		l = 255
-	}
-	return uint8(l + 0.5)
+// parseCrop parses an x,y,w,h region in source pixels.
+func parseCrop(s string) (image.Rectangle, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("want x,y,w,h, got %q", s)
+	}
+	var v [4]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return image.Rectangle{}, fmt.Errorf("invalid number %q", p)
+		}
+		v[i] = n
+	}
+	if v[0] < 0 || v[1] < 0 || v[2] <= 0 || v[3] <= 0 {
+		return image.Rectangle{}, fmt.Errorf("region %q needs x,y >= 0 and w,h > 0", s)
+	}
+	return image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]), nil
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readManifest returns the images listed in the file at p, one path or URL
+// per line. Blank lines and lines starting with # are skipped, and relative
+// paths are taken from the manifest's directory. An entry that isn't an
+// existing image file is reported to stderr and left out; bad counts them.
+func readManifest(p string) (paths []string, bad int, err error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(p)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if isURL(line) {
+			paths = append(paths, line)
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(dir, line)
+		}
+		switch {
+		case isDir(line):
+			fmt.Fprintf(os.Stderr, "error: %s:%d: %s: is a directory\n", p, n, line)
+		case !fileExists(line):
+			fmt.Fprintf(os.Stderr, "error: %s:%d: %s: no such file\n", p, n, line)
+		case !isImageExt(line) && !isSpecialFile(line):
+			fmt.Fprintf(os.Stderr, "error: %s:%d: %s: not an image file\n", p, n, line)
+		default:
+			paths = append(paths, line)
+			continue
+		}
+		bad++
+	}
+	return paths, bad, sc.Err()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"img2ascii/asciiart"
+)
+
+// capGrid shrinks the grid opts asks for img, keeping its proportions, so
+// that it is at most maxCols columns wide and maxRows rows tall. It returns
+// the grid's size before and after; they differ when it had to shrink.
+func capGrid(img image.Image, opts asciiart.Options, maxCols, maxRows int) (capped asciiart.Options, cols, rows, newCols, newRows int) {
+	cols, rows = opts.Width, opts.Height
+	b := img.Bounds()
+	if b.Empty() {
+		return opts, cols, rows, cols, rows
+	}
+	// Derive a missing dimension the way the renderer will.
+	if rows == 0 {
+		rows = int(math.Max(1, math.Round(float64(b.Dy())*opts.CharAspect*float64(cols)/float64(b.Dx()))))
+	}
+	if cols == 0 {
+		cols = int(math.Max(1, math.Round(float64(b.Dx())*float64(rows)/(float64(b.Dy())*opts.CharAspect))))
+	}
+	if cols <= maxCols && rows <= maxRows {
+		return opts, cols, rows, cols, rows
+	}
+	f := math.Min(float64(maxCols)/float64(cols), float64(maxRows)/float64(rows))
+	newCols, newRows = max(1, int(float64(cols)*f)), max(1, int(float64(rows)*f))
+	if opts.Width > 0 {
+		opts.Width = newCols
+	}
+	if opts.Height > 0 {
+		opts.Height = newRows
+	}
+	return opts, cols, rows, newCols, newRows
+}
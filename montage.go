@@ -0,0 +1,69 @@
+package main
+
+import "img2ascii/asciiart"
+
+// montageWidth is the thumbnail width, in characters, when -w is not given.
+const montageWidth = 20
+
+// montageGap is the number of blank columns between thumbnails.
+const montageGap = 2
+
+// montage tiles thumbs into one grid, cols to a band, with each thumbnail's
+// label as a caption on the row below it. Every column is as wide as the widest
+// thumbnail and every band as tall as its tallest; bands are separated by a
+// blank row, and captions are cut to the column width.
+func montage(thumbs []asciiart.Grid, labels []string, cols int) asciiart.Grid {
+	colW := 0
+	for _, t := range thumbs {
+		colW = max(colW, gridWidth(t))
+	}
+	n := min(cols, len(thumbs))
+	width := n*colW + (n-1)*montageGap
+
+	var g asciiart.Grid
+	for start := 0; start < len(thumbs); start += cols {
+		end := min(start+cols, len(thumbs))
+		bandH := 0
+		for _, t := range thumbs[start:end] {
+			bandH = max(bandH, len(t))
+		}
+		if start > 0 {
+			g = append(g, blankCells(width))
+		}
+		// One extra row leaves room for the tallest thumbnail's caption.
+		for y := 0; y <= bandH; y++ {
+			row := make([]asciiart.Cell, 0, width)
+			for i := start; i < start+n; i++ {
+				if i > start {
+					row = append(row, blankCells(montageGap)...)
+				}
+				var cells []asciiart.Cell
+				switch {
+				case i >= end:
+					// An empty slot in the last band.
+				case y < len(thumbs[i]):
+					cells = thumbs[i][y]
+				case y == len(thumbs[i]):
+					cells = caption(labels[i], colW)
+				}
+				row = append(row, cells...)
+				row = append(row, blankCells(colW-len(cells))...)
+			}
+			g = append(g, row)
+		}
+	}
+	return g
+}
+
+// caption returns label as muted cells, cut to at most w characters.
+func caption(label string, w int) []asciiart.Cell {
+	rs := []rune(label)
+	if len(rs) > w {
+		rs = rs[:w]
+	}
+	cells := make([]asciiart.Cell, len(rs))
+	for i, r := range rs {
+		cells[i] = asciiart.Cell{Ch: r, FG: mutedColor}
+	}
+	return cells
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"strings"
+
+	"img2ascii/asciiart"
+)
+
+// ansiRows encodes g as text rows. In a color mode, each run of glyphs
+// sharing a color is prefixed with its escape and every row ends with a
+// reset, so the visible width of a row is still its number of cells. Each
+// row is self-contained: it assumes nothing left by the row before, and
+// leaves nothing for the next row, a border, or the shell prompt after the
+// last row.
+func ansiRows(g asciiart.Grid, mode colorMode) []string {
+	rows := make([]string, len(g))
+	for y, row := range g {
+		var buf strings.Builder
+		// fg and bg are the escapes in effect. A cell only writes its own
+		// when they differ, which neighbors quantized to one palette entry
+		// often don't.
+		var fg, bg string
+		for _, c := range row {
+			if mode != colorNone {
+				if e := escape(writeColor, mode, c.FG); e != fg {
+					buf.WriteString(e)
+					fg = e
+				}
+				if c.HasBG {
+					if e := escape(writeBackground, mode, c.BG); e != bg {
+						buf.WriteString(e)
+						bg = e
+					}
+				} else if bg != "" {
+					// Back to the terminal's default background.
+					buf.WriteString("\x1b[49m")
+					bg = ""
+				}
+			}
+			buf.WriteRune(c.Ch)
+		}
+		if mode != colorNone {
+			buf.WriteString("\x1b[0m")
+		}
+		rows[y] = buf.String()
+	}
+	return rows
+}
+
+// escape returns the escape write writes for c in mode.
+func escape(write func(*strings.Builder, colorMode, color.RGBA), mode colorMode, c color.RGBA) string {
+	var buf strings.Builder
+	write(&buf, mode, c)
+	return buf.String()
+}
+
+// shadeRows encodes g as text rows for -shade: glyphs from the brightest
+// third of ramp are drawn bold, those from the darkest third dim, and the
+// rest at normal intensity. ramp lists the glyphs from dark pixels to light,
+// as they were picked, so a glyph's place in it is the luminance it stands
+// for; glyphs not in it, such as sparkline bars, stay normal. Like ansiRows,
+// an escape starts each run and every row ends with a reset.
+func shadeRows(g asciiart.Grid, ramp []rune) []string {
+	bands := make(map[rune]int, len(ramp))
+	for i := len(ramp) - 1; i >= 0; i-- {
+		// A glyph listed twice takes its darker place.
+		bands[ramp[i]] = shadeBand(i, len(ramp))
+	}
+	rows := make([]string, len(g))
+	for y, row := range g {
+		var buf strings.Builder
+		cur := 0
+		for _, c := range row {
+			if b := bands[c.Ch]; b != cur {
+				buf.WriteString(shadeEscapes[b+1])
+				cur = b
+			}
+			buf.WriteRune(c.Ch)
+		}
+		buf.WriteString("\x1b[0m")
+		rows[y] = buf.String()
+	}
+	return rows
+}
+
+// shadeEscapes switch to dim, normal, and bold intensity, indexed by
+// shadeBand+1. Each clears the other attribute first, since a terminal can
+// show bold and dim at once.
+var shadeEscapes = [3]string{"\x1b[22;2m", "\x1b[22m", "\x1b[22;1m"}
+
+// shadeBand returns -1, 0, or 1 for place i of an n-glyph ramp: its darkest,
+// middle, or brightest third.
+func shadeBand(i, n int) int {
+	f := float64(i) / float64(max(n-1, 1))
+	switch {
+	case f < 1.0/3:
+		return -1
+	case f > 2.0/3:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// writeRows writes each row followed by a newline.
+func writeRows(out *bufio.Writer, rows []string) {
+	for _, row := range rows {
+		out.WriteString(row)
+		out.WriteByte('\n')
+	}
+}
+
+// monoGlyphWidth is the typical advance of a monospace glyph in em, used to
+// size text so cells reproduce the character aspect ratio.
+const monoGlyphWidth = 0.6
+
+// writeHTML writes g as a <pre> block. When colored, runs of cells sharing
+// the same colors are wrapped in a single <span>.
+func writeHTML(out *bufio.Writer, g asciiart.Grid, colored bool, charAspect float64) {
+	fmt.Fprintf(out, `<pre style="font-family:monospace;font-size:10px;line-height:%.3gem;letter-spacing:0">`+"\n", monoGlyphWidth/charAspect)
+	for _, row := range g {
+		for i := 0; i < len(row); {
+			j := i + 1
+			if colored {
+				for j < len(row) && sameColors(row[i], row[j]) {
+					j++
+				}
+				fmt.Fprintf(out, `<span style="%s">`, cssColors(row[i]))
+			}
+			for _, c := range row[i:j] {
+				out.WriteString(html.EscapeString(string(c.Ch)))
+			}
+			if colored {
+				out.WriteString("</span>")
+			}
+			i = j
+		}
+		out.WriteByte('\n')
+	}
+	out.WriteString("</pre>\n")
+}
+
+func sameColors(a, b asciiart.Cell) bool {
+	return a.FG == b.FG && a.HasBG == b.HasBG && (!a.HasBG || a.BG == b.BG)
+}
+
+func cssColors(c asciiart.Cell) string {
+	s := "color:" + hexColor(c.FG)
+	if c.HasBG {
+		s += ";background-color:" + hexColor(c.BG)
+	}
+	return s
+}
+
+// hexColor formats c as #RRGGBB.
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bufio"
+	"os"
+)
+
+// pagePrompt is shown on the last line while waiting between screens.
+const pagePrompt = "\x1b[7m-- more (q to quit) --\x1b[0m"
+
+// pageRows writes rows pageSize lines at a time, waiting between screens for
+// a key on keys. It returns early when q is pressed, keys closes, or stop
+// fires.
+func pageRows(out *bufio.Writer, rows []string, pageSize int, keys <-chan byte, stop <-chan os.Signal) {
+	pageSize = max(pageSize, 1)
+	for len(rows) > 0 {
+		n := min(pageSize, len(rows))
+		writeRows(out, rows[:n])
+		rows = rows[n:]
+		if len(rows) == 0 {
+			break
+		}
+		out.WriteString(pagePrompt)
+		out.Flush()
+		var key byte
+		var ok bool
+		select {
+		case key, ok = <-keys:
+		case <-stop:
+		}
+		out.WriteString("\r\x1b[K")
+		if !ok || key == 'q' || key == 'Q' {
+			break
+		}
+	}
+	out.Flush()
+}
+
+// readKeys sends each byte read from f on the returned channel, closing it
+// at end of input.
+func readKeys(f *os.File) <-chan byte {
+	keys := make(chan byte)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 1)
+		for {
+			if n, err := f.Read(buf); n == 0 || err != nil {
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+	return keys
+}
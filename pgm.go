@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// writePGM writes lum, a luminance grid indexed by [row][column], as a
+// binary (P5) PGM image with maxval 255, one pixel per cell. Under invert
+// each value v becomes 255-v, matching the reversed ramp.
+func writePGM(out io.Writer, lum [][]uint8, invert bool) error {
+	cols := 0
+	if len(lum) > 0 {
+		cols = len(lum[0])
+	}
+	if _, err := fmt.Fprintf(out, "P5\n%d %d\n255\n", cols, len(lum)); err != nil {
+		return err
+	}
+	row := make([]byte, cols)
+	for _, r := range lum {
+		for x, v := range r {
+			if invert {
+				v = 255 - v
+			}
+			row[x] = v
+		}
+		if _, err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"testing"
+
+	"img2ascii/asciiart"
+)
+
+func TestWritePGM(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 20; y++ {
+			img.SetGray(x, y, color.Gray{uint8(x * 6)})
+		}
+	}
+	lum, err := asciiart.LuminanceGrid(img, asciiart.Options{Width: 8, Height: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, invert := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := writePGM(&buf, lum, invert); err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(&buf)
+		var magic string
+		var w, h, maxval int
+		if _, err := fmt.Fscan(r, &magic, &w, &h, &maxval); err != nil {
+			t.Fatalf("header: %v", err)
+		}
+		if magic != "P5" || w != 8 || h != 3 || maxval != 255 {
+			t.Fatalf("header %s %d %d %d, want P5 8 3 255", magic, w, h, maxval)
+		}
+		if c, _ := r.ReadByte(); c != '\n' {
+			t.Fatalf("header ends with %q, want a newline", c)
+		}
+		pix, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pix) != w*h {
+			t.Fatalf("got %d pixels, want %d", len(pix), w*h)
+		}
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				want := lum[y][x]
+				if invert {
+					want = 255 - want
+				}
+				if got := pix[y*w+x]; got != want {
+					t.Errorf("invert %v: pixel (%d, %d) = %d, want %d", invert, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	decode := func(g asciiart.Grid, opts pngOptions) *image.RGBA {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := writePNG(&buf, g, opts); err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
+		return rgba
+	}
+	// cellColors counts the pixels of each color in cell (x, y).
+	cellColors := func(img *image.RGBA, opts pngOptions, x, y int) map[color.RGBA]int {
+		n := map[color.RGBA]int{}
+		for py := y * opts.cellH; py < (y+1)*opts.cellH; py++ {
+			for px := x * opts.cellW; px < (x+1)*opts.cellW; px++ {
+				n[img.RGBAAt(px, py)]++
+			}
+		}
+		return n
+	}
+	white, black := color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255}
+	row := func(s string) []asciiart.Cell {
+		var cells []asciiart.Cell
+		for _, ch := range s {
+			cells = append(cells, asciiart.Cell{Ch: ch, FG: color.RGBA{200, 30, 30, 255}})
+		}
+		return cells
+	}
+
+	// One cell per character, a space left blank, and ink that contrasts
+	// with the background whatever the glyph's own color.
+	g := asciiart.Grid{row(" #█"), row("▀@ ")}
+	opts := pngOptions{cellW: 8, cellH: 16, bg: white}
+	img := decode(g, opts)
+	if b := img.Bounds(); b.Dx() != 24 || b.Dy() != 32 {
+		t.Fatalf("image is %dx%d, want 24x32", b.Dx(), b.Dy())
+	}
+	if n := cellColors(img, opts, 0, 0); n[white] != 8*16 {
+		t.Errorf("space: %v, want all background", n)
+	}
+	if n := cellColors(img, opts, 1, 0); n[black] == 0 || n[black]+n[white] != 8*16 {
+		t.Errorf("'#': %v, want black ink on white", n)
+	}
+	if n := cellColors(img, opts, 2, 0); n[black] != 8*16 {
+		t.Errorf("full block: %v, want all ink", n)
+	}
+	for py := 16; py < 32; py++ {
+		want := black
+		if py >= 24 {
+			want = white
+		}
+		if got := img.RGBAAt(3, py); got != want {
+			t.Errorf("upper half block at row %d: %v, want %v", py-16, got, want)
+			break
+		}
+	}
+	// The same glyph drawn in a cell twice as large covers four times the
+	// pixels, the font being scaled rather than clipped or tiled.
+	small := cellColors(img, opts, 1, 1)[black]
+	bigOpts := pngOptions{cellW: 16, cellH: 32, bg: white}
+	if big := cellColors(decode(g, bigOpts), bigOpts, 1, 1)[black]; small == 0 || big != 4*small {
+		t.Errorf("'@' inks %d pixels at 8x16 and %d at 16x32, want four times as many", small, big)
+	}
+
+	// A dark background takes white ink.
+	opts = pngOptions{cellW: 8, cellH: 16, bg: color.RGBA{20, 20, 40, 255}}
+	if n := cellColors(decode(g, opts), opts, 2, 0); n[white] != 8*16 {
+		t.Errorf("full block on dark: %v, want all white", n)
+	}
+
+	// Colored, glyphs take their cell's color and half blocks their
+	// background too.
+	red, blue := color.RGBA{200, 30, 30, 255}, color.RGBA{0, 0, 200, 255}
+	g = asciiart.Grid{{{Ch: '▀', FG: red, BG: blue, HasBG: true}, {Ch: '█', FG: red}}}
+	opts = pngOptions{colored: true, cellW: 8, cellH: 16, bg: white}
+	img = decode(g, opts)
+	if n := cellColors(img, opts, 0, 0); n[red] != 8*8 || n[blue] != 8*8 {
+		t.Errorf("colored half block: %v, want half red over half blue", n)
+	}
+	if n := cellColors(img, opts, 1, 0); n[red] != 8*16 {
+		t.Errorf("colored full block: %v, want all red", n)
+	}
+}
+
+func TestGlyphCoverage(t *testing.T) {
+	for _, tc := range []struct {
+		ch   rune
+		u, v float64
+		want float64
+	}{
+		{'█', 0.1, 0.9, 1},
+		{'▀', 0.5, 0.4, 1},
+		{'▀', 0.5, 0.6, 0},
+		{'▓', 0.5, 0.5, 0.75},
+		{'▒', 0.5, 0.5, 0.5},
+		{'░', 0.5, 0.5, 0.25},
+		// '|' is a vertical bar down the middle of the font cell.
+		{'|', 0.45, 0.5, 1},
+		{'|', 0.05, 0.5, 0},
+		// ⠁ has only the top-left dot, ⣿ all eight.
+		{'⠁', 0.25, 0.125, 1},
+		{'⠁', 0.75, 0.125, 0},
+		{'⠁', 0.25, 0.875, 0},
+		{'⣿', 0.75, 0.875, 1},
+		// Between dots is blank even when all are set.
+		{'⣿', 0.5, 0.25, 0},
+		// Anything else is a hollow box: blank in the middle and outside,
+		// inked on the ring.
+		{'λ', 0.5, 0.5, 0},
+		{'λ', 0.05, 0.5, 0},
+		{'λ', 0.2, 0.5, 1},
+	} {
+		if got := glyphCoverage(tc.ch, tc.u, tc.v, 0.5); got != tc.want {
+			t.Errorf("glyphCoverage(%q, %g, %g) = %g, want %g", tc.ch, tc.u, tc.v, got, tc.want)
+		}
+	}
+}
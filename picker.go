@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// pickFiltered runs the image picker on a terminal in key mode. Typed text
+// narrows the list to images whose file name contains it, ignoring case, and
+// Enter picks: nothing typed takes the default, a number takes that entry, and
+// text matching one image takes it. Text matching several keeps only those,
+// renumbered, so a number can pick among them. At most maxRows entries are
+// drawn.
+func pickFiltered(keys *bufio.Reader, out *bufio.Writer, cands []string, def int, remembered bool, maxRows int) string {
+	base := cands
+	query := ""
+	drawn := 0
+	for {
+		shown := filterNames(base, query)
+		if _, err := parseIndex(query, len(base)); err == nil && len(shown) == 0 {
+			// A number that no file name contains is a pick; keep the
+			// whole list in view.
+			shown = filterNames(base, "")
+		}
+		drawn = drawPicker(out, drawn, base, shown, query, def, remembered, maxRows)
+		b, err := keys.ReadByte()
+		if err != nil {
+			// End of input picks the default, as an empty line does.
+			query = ""
+			b = '\n'
+		}
+		switch {
+		case b == '\r' || b == '\n':
+			if query == "" {
+				out.WriteString("\n")
+				out.Flush()
+				return base[def]
+			}
+			if i, err := parseIndex(query, len(base)); err == nil {
+				out.WriteString("\n")
+				out.Flush()
+				return base[i]
+			}
+			switch len(shown) {
+			case 0:
+				// Not a file name here; it may be a path or URL.
+				if isURL(query) || isImageFile(query) {
+					out.WriteString("\n")
+					out.Flush()
+					return query
+				}
+				out.WriteString("\a")
+			case 1:
+				out.WriteString("\n")
+				out.Flush()
+				return base[shown[0]]
+			default:
+				narrowed := make([]string, len(shown))
+				for k, i := range shown {
+					narrowed[k] = base[i]
+				}
+				base, query, def, remembered = narrowed, "", 0, false
+			}
+		case b == 0x7f || b == '\b':
+			_, n := utf8.DecodeLastRuneInString(query)
+			query = query[:len(query)-n]
+		case b == 0x15: // Ctrl-U
+			query = ""
+		case b == 0x1b:
+			skipEscape(keys)
+		case b >= 0x20:
+			query += string(b)
+		}
+	}
+}
+
+// filterNames returns the positions in cands of the images whose base name
+// contains query, ignoring case.
+func filterNames(cands []string, query string) []int {
+	q := strings.ToLower(query)
+	var shown []int
+	for i, c := range cands {
+		if strings.Contains(strings.ToLower(filepath.Base(c)), q) {
+			shown = append(shown, i)
+		}
+	}
+	return shown
+}
+
+// drawPicker replaces the prev lines drawn last time with the entries of
+// base at the shown positions, numbered by position, and the prompt. It
+// returns the number of lines drawn.
+func drawPicker(out *bufio.Writer, prev int, base []string, shown []int, query string, def int, remembered bool, maxRows int) int {
+	if prev > 1 {
+		fmt.Fprintf(out, "\x1b[%dA", prev-1)
+	}
+	out.WriteString("\r\x1b[J")
+	out.WriteString("Select an image to render (type to filter):\n")
+	lines := 1
+	for k, i := range shown {
+		if k == maxRows {
+			fmt.Fprintf(out, "  ... %d more\n", len(shown)-k)
+			lines++
+			break
+		}
+		mark := ""
+		if remembered && i == def {
+			mark = " (last choice)"
+		}
+		fmt.Fprintf(out, "  %d) %s%s\n", i+1, base[i], mark)
+		lines++
+	}
+	fmt.Fprintf(out, "Filter, or number (1-%d) (default %d): %s", len(base), def+1, query)
+	out.Flush()
+	return lines + 1
+}
+
+// skipEscape discards the rest of an escape sequence, such as an arrow key,
+// whose ESC has been read.
+func skipEscape(keys *bufio.Reader) {
+	b, err := keys.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return
+	}
+	for {
+		// CSI parameters end at a final byte in @..~.
+		b, err := keys.ReadByte()
+		if err != nil || (b >= 0x40 && b <= 0x7e) {
+			return
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"img2ascii/asciiart"
+)
+
+// pngOptions controls writePNG.
+type pngOptions struct {
+	colored      bool
+	cellW, cellH int        // character cell size in pixels
+	bg           color.RGBA // canvas color; uncolored glyphs use black or white to contrast
+}
+
+// writePNG draws g onto an image with one cellW x cellH cell per character
+// and encodes it as PNG. ASCII glyphs come from font8x16 scaled to the cell;
+// half blocks, shade blocks and Braille dots are drawn as shapes, and other
+// characters as a hollow box.
+func writePNG(out io.Writer, g asciiart.Grid, opts pngOptions) error {
+	cols := 0
+	if len(g) > 0 {
+		cols = len(g[0])
+	}
+	img := image.NewRGBA(image.Rect(0, 0, cols*opts.cellW, len(g)*opts.cellH))
+	draw.Draw(img, img.Bounds(), image.NewUniform(opts.bg), image.Point{}, draw.Src)
+
+	ink := color.RGBA{0, 0, 0, 0xff}
+	if asciiart.Luminance8(uint32(opts.bg.R)<<8, uint32(opts.bg.G)<<8, uint32(opts.bg.B)<<8) < 128 {
+		ink = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	}
+	for y, row := range g {
+		for x, c := range row {
+			cell := image.Rect(x*opts.cellW, y*opts.cellH, (x+1)*opts.cellW, (y+1)*opts.cellH)
+			fg := ink
+			if opts.colored {
+				fg = opaque(c.FG)
+				if c.HasBG {
+					draw.Draw(img, cell, image.NewUniform(opaque(c.BG)), image.Point{}, draw.Src)
+				}
+			}
+			drawGlyph(img, cell, c.Ch, fg)
+		}
+	}
+	return png.Encode(out, img)
+}
+
+func opaque(c color.RGBA) color.RGBA {
+	c.A = 0xff
+	return c
+}
+
+// drawGlyph blends ch in color fg into the cell r of img.
+func drawGlyph(img *image.RGBA, r image.Rectangle, ch rune, fg color.RGBA) {
+	if ch == ' ' {
+		return
+	}
+	w, h := float64(r.Dx()), float64(r.Dy())
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			u := (float64(px-r.Min.X) + 0.5) / w
+			v := (float64(py-r.Min.Y) + 0.5) / h
+			cov := glyphCoverage(ch, u, v, w/h)
+			if cov <= 0 {
+				continue
+			}
+			i := img.PixOffset(px, py)
+			p := img.Pix[i : i+3 : i+3]
+			p[0] = uint8(float64(p[0])*(1-cov) + float64(fg.R)*cov + 0.5)
+			p[1] = uint8(float64(p[1])*(1-cov) + float64(fg.G)*cov + 0.5)
+			p[2] = uint8(float64(p[2])*(1-cov) + float64(fg.B)*cov + 0.5)
+		}
+	}
+}
+
+// brailleDotBits holds the dot bit of each position of a Braille cell,
+// indexed by [row][column].
+var brailleDotBits = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// glyphCoverage returns how much of the point (u, v), both 0..1 across a cell
+// whose width is aspect times its height, ch covers: 0 for none, 1 for full.
+func glyphCoverage(ch rune, u, v, aspect float64) float64 {
+	switch {
+	case ch >= 0x20 && ch <= 0x7e:
+		row := font8x16[ch-0x20][int(v*16)]
+		if row&(0x80>>int(u*8)) != 0 {
+			return 1
+		}
+		return 0
+	case ch == '▀':
+		if v < 0.5 {
+			return 1
+		}
+		return 0
+	case ch == '█':
+		return 1
+	case ch == '▓':
+		return 0.75
+	case ch == '▒':
+		return 0.5
+	case ch == '░':
+		return 0.25
+	case ch >= 0x2800 && ch <= 0x28ff:
+		// A round dot centered in each of the 2x4 positions.
+		col, row := int(u*2), int(v*4)
+		if (ch-0x2800)&brailleDotBits[row][col] == 0 {
+			return 0
+		}
+		du := (u*2 - float64(col) - 0.5) * aspect * 2
+		dv := v*4 - float64(row) - 0.5
+		if math.Hypot(du, dv) < 0.35 {
+			return 1
+		}
+		return 0
+	default:
+		// An unknown character: a hollow box inset from the cell edges.
+		if u < 0.15 || u > 0.85 || v < 0.2 || v > 0.8 {
+			return 0
+		}
+		if u < 0.3 || u > 0.7 || v < 0.3 || v > 0.7 {
+			return 1
+		}
+		return 0
+	}
+}
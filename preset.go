@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+
+	"img2ascii/asciiart"
+)
+
+// presets are named bundles of flag values for common rendering styles,
+// picked with -preset. They are flag values rather than asciiart.Options
+// because some, such as -edges and -halfblock, choose a renderer.
+var presets = map[string]map[string]string{
+	// Photos: average out noise, lift the midtones, and use the full ramp.
+	"photo": {"sample": "average", "gamma": "2.2", "autocontrast": "true"},
+	// Line art and diagrams: outlines only.
+	"lineart": {"edges": "true"},
+	// Colored half blocks, two pixel rows per character.
+	"blocks": {"halfblock": "true", "color": "true"},
+	// The plain defaults, overriding a config file's charset.
+	"classic": {"sample": "nearest", "gamma": "1", "autocontrast": "false", "chars": asciiart.DefaultCharset},
+}
+
+// presetConflicts lists, for each flag a preset or the config file may
+// set, the flags that rule it out when they are already set, so that
+// -preset lineart -braille draws Braille rather than failing on -edges.
+var presetConflicts = map[string][]string{
+	"edges":     {"braille", "halfblock", "pixel", "bw", "supersample", "flip-y"},
+	"halfblock": {"braille", "pixel", "edges", "bw", "alpha", "shade", "flip-y"},
+	"color":     {"color256", "color16", "alpha", "shade"},
+	"color256":  {"color", "color16", "alpha", "shade"},
+	"color16":   {"color", "color256", "alpha", "shade"},
+	"sample":    {"supersample"},
+	"w":         {"h"},
+}
+
+// ruledOut reports whether a preset or config value for the named flag
+// must be skipped: the flag is set already, or so is one it conflicts with.
+func ruledOut(flags *flag.FlagSet, name string) bool {
+	if flagSet(flags, name) {
+		return true
+	}
+	for _, other := range presetConflicts[name] {
+		if flagSet(flags, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPreset sets each flag in preset that wasn't given on the command
+// line, unless a flag that was given conflicts with it.
+func applyPreset(flags *flag.FlagSet, preset map[string]string) {
+	for name, value := range preset {
+		if !ruledOut(flags, name) {
+			flags.Set(name, value)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestApplyPresetConflicts(t *testing.T) {
+	for _, tc := range []struct {
+		preset string
+		args   []string
+		set    []string // flags the preset should set
+		skip   []string // flags it should leave alone
+	}{
+		{"lineart", nil, []string{"edges"}, nil},
+		{"lineart", []string{"-braille"}, nil, []string{"edges"}},
+		{"blocks", []string{"-braille"}, []string{"color"}, []string{"halfblock"}},
+		{"blocks", []string{"-color256"}, []string{"halfblock"}, []string{"color"}},
+		{"blocks", []string{"-alpha"}, nil, []string{"halfblock", "color"}},
+		{"photo", []string{"-supersample", "2"}, []string{"gamma", "autocontrast"}, []string{"sample"}},
+		{"photo", []string{"-gamma", "1.5"}, []string{"sample"}, []string{"gamma"}},
+	} {
+		flags := flag.NewFlagSet("render", flag.ContinueOnError)
+		for _, name := range []string{"edges", "braille", "halfblock", "pixel", "bw", "flip-y", "color", "color256", "color16", "alpha", "shade", "autocontrast"} {
+			flags.Bool(name, false, "")
+		}
+		flags.Int("supersample", 1, "")
+		flags.String("sample", "nearest", "")
+		flags.Float64("gamma", 1, "")
+		if err := flags.Parse(tc.args); err != nil {
+			t.Fatal(err)
+		}
+		before := map[string]string{}
+		flags.VisitAll(func(f *flag.Flag) { before[f.Name] = f.Value.String() })
+		applyPreset(flags, presets[tc.preset])
+		for _, name := range tc.set {
+			if got, want := flags.Lookup(name).Value.String(), presets[tc.preset][name]; got != want {
+				t.Errorf("-preset %s %v: -%s = %s, want %s", tc.preset, tc.args, name, got, want)
+			}
+		}
+		for _, name := range tc.skip {
+			if got := flags.Lookup(name).Value.String(); got != before[name] {
+				t.Errorf("-preset %s %v: -%s = %s, want it left at %s", tc.preset, tc.args, name, got, before[name])
+			}
+		}
+	}
+}
+
+func TestApplyConfigConflicts(t *testing.T) {
+	newFlags := func(args ...string) *flag.FlagSet {
+		flags := flag.NewFlagSet("render", flag.ContinueOnError)
+		flags.Int("w", 80, "")
+		flags.Int("h", 0, "")
+		for _, name := range []string{"color", "color256", "color16", "alpha", "shade", "halfblock"} {
+			flags.Bool(name, false, "")
+		}
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return flags
+	}
+	width, color := 100, "truecolor"
+	c := config{Width: &width, Color: &color}
+	fail := func(err error) { t.Errorf("unexpected warning: %v", err) }
+
+	flags := newFlags()
+	applyConfig(flags, c, fail)
+	if !flagSet(flags, "w") || !flagSet(flags, "color") {
+		t.Errorf("config width and color not applied")
+	}
+	// -h derives the width, and -alpha draws without color.
+	flags = newFlags("-h", "10", "-alpha")
+	applyConfig(flags, c, fail)
+	if flagSet(flags, "w") || flagSet(flags, "color") {
+		t.Errorf("config width or color applied over -h and -alpha")
+	}
+	// A preset's color wins over the file's.
+	flags = newFlags()
+	applyPreset(flags, presets["blocks"])
+	color = "256"
+	applyConfig(flags, c, fail)
+	if flagSet(flags, "color256") {
+		t.Errorf("config -color256 applied over the preset's -color")
+	}
+}
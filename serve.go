@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"img2ascii/asciiart"
+)
+
+// Limits on the work a single -serve request can cause.
+const (
+	serveTimeout       = 30 * time.Second // the whole request, fetch included
+	maxServeImageBytes = 32 << 20         // fetched image file
+	maxServePixels     = 50_000_000       // decoded image, checked before decoding
+	maxServeWidth      = 1000             // w parameter
+	maxServeCells      = 4_000_000        // output characters
+)
+
+// renderServer answers the -serve endpoint GET /render?url=...&w=...&
+// invert=...&format=text|html. Everything but the image, width, inversion
+// and format comes from the command-line flags.
+type renderServer struct {
+	opts       asciiart.Options
+	render     func(image.Image, asciiart.Options) (asciiart.Grid, error)
+	format     string // default format, text or html
+	colored    bool   // color the html format
+	charAspect float64
+	cellSize   float64 // raster pixels per column for SVG input
+}
+
+// serve runs an HTTP server for rs on addr. It returns only when the server
+// fails.
+func serve(addr string, rs *renderServer) error {
+	mux := http.NewServeMux()
+	mux.Handle("/render", http.TimeoutHandler(rs, serveTimeout, "render timed out\n"))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      serveTimeout + 5*time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+func (rs *renderServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	src := q.Get("url")
+	if !isURL(src) {
+		http.Error(w, "url must be an http(s) URL", http.StatusBadRequest)
+		return
+	}
+	opts := rs.opts
+	if s := q.Get("w"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxServeWidth {
+			http.Error(w, fmt.Sprintf("w must be between 1 and %d", maxServeWidth), http.StatusBadRequest)
+			return
+		}
+		opts.Width, opts.Height = n, 0
+	}
+	if s := q.Get("invert"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			http.Error(w, "invert must be true or false", http.StatusBadRequest)
+			return
+		}
+		opts.Invert = b
+	}
+	format := rs.format
+	if s := q.Get("format"); s != "" {
+		format = s
+	}
+	if format != "text" && format != "html" {
+		http.Error(w, "format must be text or html", http.StatusBadRequest)
+		return
+	}
+
+	img, status, err := rs.load(r.Context(), src, opts)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if b := img.Bounds(); opts.Height == 0 && !b.Empty() {
+		// A very tall, narrow image would otherwise make an enormous grid.
+		rows := float64(b.Dy()) * opts.CharAspect * float64(opts.Width) / float64(b.Dx())
+		if rows*float64(opts.Width) > maxServeCells {
+			http.Error(w, "image too tall for the requested width", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	g, err := rs.render(img, opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("render: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	if format == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writeHTML(out, g, rs.colored, rs.charAspect)
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeRows(out, g.Text())
+	}
+	out.Flush()
+	w.Write(buf.Bytes())
+}
+
+// load fetches and decodes the image at src, enforcing the -serve limits.
+// On error, status is the HTTP status to answer with: 502 or 504 when the
+// fetch fails, 413 over a limit, 415 for an unknown format, and 422 when the
+// image can't be decoded.
+func (rs *renderServer) load(ctx context.Context, src string, opts asciiart.Options) (img image.Image, status int, err error) {
+	body, err := fetchURLContext(ctx, src)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, http.StatusGatewayTimeout, err
+		}
+		return nil, http.StatusBadGateway, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, maxServeImageBytes+1))
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("fetch: %w", err)
+	}
+	if len(data) > maxServeImageBytes {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("image larger than %d MiB", maxServeImageBytes>>20)
+	}
+
+	// decodeSVG bounds its own raster; other formats are checked before
+	// their pixels are allocated.
+	if !isSVG(data) {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if errors.Is(err, image.ErrFormat) {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("decode: %w", err)
+		}
+		if err != nil {
+			return nil, http.StatusUnprocessableEntity, fmt.Errorf("decode: %w", err)
+		}
+		if cfg.Width*cfg.Height > maxServePixels {
+			return nil, http.StatusRequestEntityTooLarge, &pixelLimitError{width: cfg.Width, height: cfg.Height, limit: maxServePixels}
+		}
+	}
+	if bytes.HasPrefix(data, []byte("GIF8")) {
+		// Only the first frame is rendered, so don't composite the rest.
+		img, _, err = image.Decode(bytes.NewReader(data))
+	} else {
+		// Likewise only the first page of a TIFF is decoded. The check
+		// above saw only its first IFD, so the page is checked itself.
+		var anim animation
+		anim, err = decodeAnimation(bytes.NewReader(data), decodeOptions{
+			autorotate: true,
+			svgSize:    func(w, h float64) (int, int, error) { return svgRasterSize(w, h, opts, rs.cellSize) },
+			maxPixels:  maxServePixels,
+		})
+		if err == nil {
+			img = anim.frames[0]
+		}
+	}
+	var pe *pixelLimitError
+	if errors.As(err, &pe) {
+		return nil, http.StatusRequestEntityTooLarge, err
+	}
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, fmt.Errorf("decode: %w", err)
+	}
+	return img, 0, nil
+}
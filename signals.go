@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/signal"
+)
+
+// restoreTerminal resets colors and shows the cursor again, undoing what the
+// interactive modes may have left behind.
+const restoreTerminal = "\x1b[0m\x1b[?25h"
+
+// notifyStop relays stopSignals to the returned channel instead of letting
+// them kill the process, so a mode that changed the terminal can wind down and
+// put it back. The relay stays in place until the process exits.
+func notifyStop() <-chan os.Signal {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, stopSignals...)
+	return stop
+}
+
+// exitOnStop is for code that blocks where it can't watch a stop channel. On
+// one of stopSignals it runs restore, writes restoreTerminal to w and exits
+// with status 130. cancel stops waiting.
+func exitOnStop(w io.Writer, restore func()) (cancel func()) {
+	stop := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(stop, stopSignals...)
+	go func() {
+		select {
+		case <-stop:
+			restore()
+			io.WriteString(w, restoreTerminal)
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(stop)
+		close(done)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"image"
+
+	"img2ascii/asciiart"
+)
+
+// sparkBlocks are the sparkline's bars, from the darkest mean luminance to
+// the brightest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkBlock returns the bar for a mean luminance l, 0..255.
+func sparkBlock(l float64) rune {
+	return sparkBlocks[int(l*float64(len(sparkBlocks)-1)/255+0.5)]
+}
+
+// addSparklines returns g, rendered from img with opts, with sparklines of
+// its mean luminance: a bar per row down the right side, after a blank
+// column, and a bar per column along the bottom. The luminance is sampled on
+// g's own grid, so the bars line up with every rendering mode.
+func addSparklines(img image.Image, g asciiart.Grid, opts asciiart.Options) (asciiart.Grid, error) {
+	w := gridWidth(g)
+	opts.Width, opts.Height = w, len(g)
+	lum, err := asciiart.LuminanceGrid(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]float64, w)
+	out := make(asciiart.Grid, 0, len(g)+1)
+	for y, row := range lum {
+		sum := 0.0
+		for x, l := range row {
+			sum += float64(l)
+			cols[x] += float64(l)
+		}
+		out = append(out, append(g[y][:w:w], asciiart.Cell{Ch: ' '}, asciiart.Cell{Ch: sparkBlock(sum / float64(w)), FG: mutedColor}))
+	}
+	bottom := make([]asciiart.Cell, 0, w+2)
+	for _, sum := range cols {
+		bottom = append(bottom, asciiart.Cell{Ch: sparkBlock(sum / float64(len(g))), FG: mutedColor})
+	}
+	return append(out, append(bottom, blankCells(2)...)), nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"img2ascii/asciiart"
+)
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// renderStats collects what -stats reports about one input.
+type renderStats struct {
+	format     string
+	bytes      int64
+	frames     int
+	srcW, srcH int
+	cols, rows int
+	decode     time.Duration // reading and decoding the file
+	render     time.Duration // everything between decoding and writing
+	write      time.Duration // encoding and writing the output
+	timings    asciiart.Timings
+}
+
+// print writes s to w as an indented report on path. The sampling and glyph
+// phases are only known for the glyph ramp renderers; the others report the
+// render total alone.
+func (s *renderStats) print(w io.Writer, path string) {
+	fmt.Fprintf(w, "stats for %s:\n", path)
+	fmt.Fprintf(w, "  format  %s, %d bytes read\n", s.format, s.bytes)
+	frames := "1 frame"
+	if s.frames != 1 {
+		frames = fmt.Sprintf("%d frames", s.frames)
+	}
+	fmt.Fprintf(w, "  source  %dx%d pixels, %s\n", s.srcW, s.srcH, frames)
+	fmt.Fprintf(w, "  grid    %dx%d characters\n", s.cols, s.rows)
+	fmt.Fprintf(w, "  decode  %v\n", s.decode.Round(time.Microsecond))
+	fmt.Fprintf(w, "  render  %v\n", s.render.Round(time.Microsecond))
+	if s.timings != (asciiart.Timings{}) {
+		fmt.Fprintf(w, "    sample  %v\n", s.timings.Sample.Round(time.Microsecond))
+		fmt.Fprintf(w, "    glyphs  %v\n", s.timings.Glyphs.Round(time.Microsecond))
+	}
+	fmt.Fprintf(w, "  write   %v\n", s.write.Round(time.Microsecond))
+}
+
+// extFormats maps image file extensions to the decoder name they imply.
+var extFormats = map[string]string{
+	".png": "png", ".jpg": "jpeg", ".jpeg": "jpeg", ".gif": "gif", ".bmp": "bmp",
+	".tif": "tiff", ".tiff": "tiff", ".webp": "webp", ".svg": "svg",
+}
+
+// formatLine describes the format the decoder found for the image at p,
+// noting when p's extension claims another.
+func formatLine(p, format string) string {
+	ext := strings.ToLower(filepath.Ext(p))
+	if want, ok := extFormats[ext]; ok && want != format && !isURL(p) {
+		return fmt.Sprintf("%s: %s (named %s)", p, format, ext)
+	}
+	return fmt.Sprintf("%s: %s", p, format)
+}
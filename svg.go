@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"img2ascii/asciiart"
+)
+
+// svgOptions controls writeSVG.
+type svgOptions struct {
+	colored      bool
+	cellW, cellH float64 // character cell size in SVG user units
+	bg           string  // #RRGGBB fill behind the art; empty for transparent
+}
+
+// writeSVG writes g as an SVG document with one <text> element per row. Rows
+// are stretched to exactly the grid width so alignment doesn't depend on the
+// viewer's monospace font. When colored, runs of equal color become <tspan>
+// elements, and half-block backgrounds are drawn as <rect> runs behind them.
+func writeSVG(out *bufio.Writer, g asciiart.Grid, opts svgOptions) {
+	cols := 0
+	if len(g) > 0 {
+		cols = len(g[0])
+	}
+	width := float64(cols) * opts.cellW
+	height := float64(len(g)) * opts.cellH
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		svgNum(width), svgNum(height), svgNum(width), svgNum(height))
+	if opts.bg != "" {
+		fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", opts.bg)
+	}
+	fmt.Fprintf(out, `<g font-family="monospace" font-size="%s" xml:space="preserve">`+"\n", svgNum(opts.cellW/monoGlyphWidth))
+
+	for y, row := range g {
+		top := float64(y) * opts.cellH
+		if opts.colored {
+			for i := 0; i < len(row); {
+				j := i + 1
+				for j < len(row) && row[j].HasBG == row[i].HasBG && row[j].BG == row[i].BG {
+					j++
+				}
+				if row[i].HasBG {
+					fmt.Fprintf(out, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+						svgNum(float64(i)*opts.cellW), svgNum(top), svgNum(float64(j-i)*opts.cellW), svgNum(opts.cellH), hexColor(row[i].BG))
+				}
+				i = j
+			}
+		}
+
+		// Place the baseline about 80% of the way down the cell.
+		fmt.Fprintf(out, `<text x="0" y="%s" textLength="%s" lengthAdjust="spacing">`,
+			svgNum(top+0.8*opts.cellH), svgNum(float64(len(row))*opts.cellW))
+		for i := 0; i < len(row); {
+			j := i + 1
+			if opts.colored {
+				for j < len(row) && row[j].FG == row[i].FG {
+					j++
+				}
+				fmt.Fprintf(out, `<tspan fill="%s">`, hexColor(row[i].FG))
+			}
+			var run strings.Builder
+			for _, c := range row[i:j] {
+				run.WriteRune(c.Ch)
+			}
+			xml.EscapeText(out, []byte(run.String()))
+			if opts.colored {
+				out.WriteString("</tspan>")
+			}
+			i = j
+		}
+		out.WriteString("</text>\n")
+	}
+	out.WriteString("</g>\n</svg>\n")
+}
+
+// svgNum formats v compactly for an SVG attribute, to at most 3 decimals.
+func svgNum(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 3, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// parseHexColor parses a #RRGGBB color.
+func parseHexColor(s string) (color.RGBA, error) {
+	var c color.RGBA
+	if len(s) != 7 || s[0] != '#' {
+		return c, fmt.Errorf("invalid color %q (want #RRGGBB)", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return c, fmt.Errorf("invalid color %q (want #RRGGBB)", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, nil
+}
+
+// bgColorNames are the color names parseColor accepts besides #RRGGBB.
+var bgColorNames = map[string]color.RGBA{
+	"black": {0, 0, 0, 0xff},
+	"white": {0xff, 0xff, 0xff, 0xff},
+	"gray":  {0x80, 0x80, 0x80, 0xff},
+	"grey":  {0x80, 0x80, 0x80, 0xff},
+}
+
+// parseColor parses a #RRGGBB color or one of bgColorNames, ignoring case.
+func parseColor(s string) (color.RGBA, error) {
+	if c, ok := bgColorNames[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	c, err := parseHexColor(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid color %q (want #RRGGBB, black, white, or gray)", s)
+	}
+	return c, nil
+}
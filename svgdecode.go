@@ -0,0 +1,886 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/vector"
+
+	"img2ascii/asciiart"
+)
+
+const (
+	maxSVGPixels = 8192       // each side of the raster an SVG is drawn to
+	maxSVGArea   = 50_000_000 // pixels of the raster svgRasterSize asks for
+)
+
+// isSVG reports whether head, the start of a file, looks like an SVG
+// document.
+func isSVG(head []byte) bool {
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	return bytes.HasPrefix(head, []byte("<")) && bytes.Contains(head, []byte("<svg"))
+}
+
+// decodeSVG draws the SVG document read from r onto a transparent image.
+// size picks the image's pixel size from the document's intrinsic width and
+// height. It covers the common static subset of SVG: shapes and paths with
+// solid fills and strokes, transforms, groups, <use>, class and id style
+// rules, and embedded images. Gradients are drawn in their average color;
+// text, clipping, masks, filters, and dashes are ignored. References to
+// external files are an error, since nothing is fetched.
+func decodeSVG(r io.Reader, size func(w, h float64) (int, int, error)) (image.Image, error) {
+	root, err := parseSVGTree(r)
+	if err != nil {
+		return nil, fmt.Errorf("svg: %w", err)
+	}
+	doc := &svgDoc{ids: map[string]*svgNode{}}
+	if err := doc.index(root); err != nil {
+		return nil, fmt.Errorf("svg: %w", err)
+	}
+	vb, w, h := svgViewport(root)
+	pw, ph, err := size(w, h)
+	if err != nil {
+		return nil, fmt.Errorf("svg: %w", err)
+	}
+	if f := float64(maxSVGPixels) / float64(max(pw, ph)); f < 1 {
+		pw, ph = max(1, int(float64(pw)*f)), max(1, int(float64(ph)*f))
+	}
+	rd := &svgRenderer{
+		doc: doc,
+		dst: image.NewRGBA(image.Rect(0, 0, pw, ph)),
+		z:   vector.NewRasterizer(pw, ph),
+	}
+	ctm := viewBoxTransform(vb, float64(pw), float64(ph), root.attrs["preserveAspectRatio"])
+	if err := rd.draw(root, ctm, map[string]string{}, 1, 0); err != nil {
+		return nil, fmt.Errorf("svg: %w", err)
+	}
+	return rd.dst, nil
+}
+
+// svgRasterSize returns the raster size for an SVG of intrinsic size w x h
+// rendered with opts: cellSize pixels per output column.
+func svgRasterSize(w, h float64, opts asciiart.Options, cellSize float64) (int, int, error) {
+	cols := float64(opts.Width)
+	if cols == 0 {
+		cols = float64(opts.Height) * w / (h * opts.CharAspect)
+	}
+	pw := cols * cellSize
+	return svgPixels(pw, pw*h/w)
+}
+
+// svgPixels rounds a raster size of w x h pixels, failing when it is over
+// maxSVGArea, as a sliver of a document drawn at a fixed width can be.
+func svgPixels(w, h float64) (int, int, error) {
+	w, h = math.Max(1, math.Round(w)), math.Max(1, math.Round(h))
+	if !(w*h <= maxSVGArea) {
+		return 0, 0, fmt.Errorf("raster of %.0fx%.0f pixels is over the limit of %d", w, h, maxSVGArea)
+	}
+	return int(w), int(h), nil
+}
+
+// svgNode is an element of an SVG document.
+type svgNode struct {
+	name     string
+	attrs    map[string]string
+	children []*svgNode
+	text     string // character data, kept for <style>
+}
+
+// parseSVGTree reads the element tree of an SVG document, keyed by local
+// names so xlink:href is read as href.
+func parseSVGTree(r io.Reader) (*svgNode, error) {
+	d := xml.NewDecoder(r)
+	// Editors declare their own entities in a DTD, which the decoder
+	// doesn't read.
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	var stack []*svgNode
+	var root *svgNode
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &svgNode{name: t.Name.Local, attrs: map[string]string{}}
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil || root.name != "svg" {
+		return nil, errors.New("no <svg> root element")
+	}
+	return root, nil
+}
+
+// svgDoc holds what is looked up across an SVG document.
+type svgDoc struct {
+	ids   map[string]*svgNode
+	rules []cssRule
+}
+
+// cssRule is a rule of a <style> sheet with a single simple selector: an
+// element name, a .class, an #id, or a name.class.
+type cssRule struct {
+	name, class, id string
+	specificity     int
+	decls           map[string]string
+}
+
+// index records the elements with an id and the rules of every <style>
+// sheet under n.
+func (d *svgDoc) index(n *svgNode) error {
+	if id := n.attrs["id"]; id != "" {
+		if _, dup := d.ids[id]; !dup {
+			d.ids[id] = n
+		}
+	}
+	if n.name == "style" {
+		if err := d.parseCSS(n.text); err != nil {
+			return err
+		}
+	}
+	for _, c := range n.children {
+		if err := d.index(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCSS adds the rules of a style sheet. Selectors other than the simple
+// ones cssRule describes are skipped, as are at-rules except @import, which
+// would need another file.
+func (d *svgDoc) parseCSS(css string) error {
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			css = css[:start]
+			break
+		}
+		css = css[:start] + css[start+2+end+2:]
+	}
+	for css = strings.TrimSpace(css); css != ""; css = strings.TrimSpace(css) {
+		if strings.HasPrefix(css, "@import") {
+			return errors.New("style sheet @import of another file is not supported")
+		}
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			break
+		}
+		// Skip to the matching brace, so nested at-rule blocks go as a whole.
+		depth, end := 0, len(css)
+		for i := open; i < len(css); i++ {
+			if css[i] == '{' {
+				depth++
+			} else if css[i] == '}' {
+				if depth--; depth == 0 {
+					end = i
+					break
+				}
+			}
+		}
+		selectors, body := css[:open], css[open+1:end]
+		css = css[min(end+1, len(css)):]
+		if strings.HasPrefix(strings.TrimSpace(selectors), "@") {
+			continue
+		}
+		decls := parseDecls(body)
+		for _, sel := range strings.Split(selectors, ",") {
+			if rule, ok := parseSelector(strings.TrimSpace(sel)); ok {
+				rule.decls = decls
+				d.rules = append(d.rules, rule)
+			}
+		}
+	}
+	// Later rules win among equals, so a stable sort keeps source order.
+	sort.SliceStable(d.rules, func(i, j int) bool { return d.rules[i].specificity < d.rules[j].specificity })
+	return nil
+}
+
+func parseSelector(sel string) (cssRule, bool) {
+	if sel == "" || strings.ContainsAny(sel, " >+~:[*") {
+		return cssRule{}, false
+	}
+	if id, ok := strings.CutPrefix(sel, "#"); ok {
+		return cssRule{id: id, specificity: 100}, true
+	}
+	name, class, _ := strings.Cut(sel, ".")
+	if strings.Contains(class, ".") || strings.Contains(name, "#") {
+		return cssRule{}, false
+	}
+	rule := cssRule{name: name, class: class}
+	if name != "" {
+		rule.specificity++
+	}
+	if class != "" {
+		rule.specificity += 10
+	}
+	return rule, true
+}
+
+func (r cssRule) matches(n *svgNode) bool {
+	if r.id != "" {
+		return n.attrs["id"] == r.id
+	}
+	if r.name != "" && r.name != n.name {
+		return false
+	}
+	return r.class == "" || containsField(n.attrs["class"], r.class)
+}
+
+func containsField(s, f string) bool {
+	for _, x := range strings.Fields(s) {
+		if x == f {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDecls parses CSS declarations such as "fill:red; stroke:none".
+func parseDecls(s string) map[string]string {
+	decls := map[string]string{}
+	for _, decl := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "!important"))
+		decls[strings.TrimSpace(k)] = v
+	}
+	return decls
+}
+
+// svgStyleProps are the properties decodeSVG uses. All but display and
+// opacity are inherited.
+var svgStyleProps = []string{
+	"fill", "fill-opacity", "stroke", "stroke-width", "stroke-opacity",
+	"color", "visibility", "display", "opacity",
+}
+
+// style returns n's computed properties given its parent's: presentation
+// attributes, then matching style rules, then its style attribute.
+func (d *svgDoc) style(n *svgNode, parent map[string]string) map[string]string {
+	st := make(map[string]string, len(svgStyleProps))
+	for k, v := range parent {
+		if k != "display" && k != "opacity" {
+			st[k] = v
+		}
+	}
+	set := func(decls map[string]string) {
+		for _, k := range svgStyleProps {
+			if v, ok := decls[k]; ok && v != "inherit" {
+				st[k] = v
+			}
+		}
+	}
+	set(n.attrs)
+	for _, r := range d.rules {
+		if r.matches(n) {
+			set(r.decls)
+		}
+	}
+	if s, ok := n.attrs["style"]; ok {
+		set(parseDecls(s))
+	}
+	return st
+}
+
+// paint resolves a fill or stroke value to a color; ok is false for none.
+func (d *svgDoc) paint(v, current string) (c color.NRGBA, ok bool, err error) {
+	v = strings.TrimSpace(v)
+	switch v {
+	case "none", "transparent":
+		return c, false, nil
+	case "currentColor":
+		if current == "" || current == "currentColor" {
+			return color.NRGBA{0, 0, 0, 0xff}, true, nil
+		}
+		return d.paint(current, "")
+	}
+	if rest, found := strings.CutPrefix(v, "url("); found {
+		ref, fallback, _ := strings.Cut(rest, ")")
+		ref = strings.Trim(strings.TrimSpace(ref), `'"`)
+		id, local := strings.CutPrefix(ref, "#")
+		if !local {
+			return c, false, fmt.Errorf("paint server in another file (%s) is not supported", ref)
+		}
+		if g, found := d.ids[id]; found {
+			if c, ok := d.gradientColor(g, 0); ok {
+				return c, true, nil
+			}
+		}
+		// A missing or unusable paint server falls back as the spec says.
+		if fallback = strings.TrimSpace(fallback); fallback != "" {
+			return d.paint(fallback, current)
+		}
+		return c, false, nil
+	}
+	c, ok = parseCSSColor(v)
+	return c, ok, nil
+}
+
+// gradientColor returns the average color of gradient g's stops, following
+// href to the gradient that holds them.
+func (d *svgDoc) gradientColor(g *svgNode, depth int) (color.NRGBA, bool) {
+	if g.name != "linearGradient" && g.name != "radialGradient" || depth > 8 {
+		return color.NRGBA{}, false
+	}
+	var r, gr, b, a, n float64
+	for _, stop := range g.children {
+		if stop.name != "stop" {
+			continue
+		}
+		st := d.style(stop, nil)
+		decls := parseDecls(stop.attrs["style"])
+		sc, ok := parseCSSColor(firstOf(decls["stop-color"], stop.attrs["stop-color"], "black"))
+		if !ok {
+			continue
+		}
+		op := parseOpacity(firstOf(decls["stop-opacity"], stop.attrs["stop-opacity"], st["opacity"]))
+		alpha := float64(sc.A) * op
+		// Average premultiplied, so transparent stops don't darken.
+		r += float64(sc.R) * alpha
+		gr += float64(sc.G) * alpha
+		b += float64(sc.B) * alpha
+		a += alpha
+		n++
+	}
+	if n == 0 {
+		if ref, ok := strings.CutPrefix(g.attrs["href"], "#"); ok {
+			if next, found := d.ids[ref]; found {
+				return d.gradientColor(next, depth+1)
+			}
+		}
+		return color.NRGBA{}, false
+	}
+	if a == 0 {
+		return color.NRGBA{}, true
+	}
+	return color.NRGBA{uint8(r/a + 0.5), uint8(gr/a + 0.5), uint8(b/a + 0.5), uint8(a/n + 0.5)}, true
+}
+
+func firstOf(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// namedColors holds the CSS color keywords most used in SVG files.
+var namedColors = map[string]color.NRGBA{
+	"black": {0, 0, 0, 255}, "white": {255, 255, 255, 255}, "red": {255, 0, 0, 255},
+	"green": {0, 128, 0, 255}, "lime": {0, 255, 0, 255}, "blue": {0, 0, 255, 255},
+	"yellow": {255, 255, 0, 255}, "cyan": {0, 255, 255, 255}, "aqua": {0, 255, 255, 255},
+	"magenta": {255, 0, 255, 255}, "fuchsia": {255, 0, 255, 255}, "gray": {128, 128, 128, 255},
+	"grey": {128, 128, 128, 255}, "silver": {192, 192, 192, 255}, "maroon": {128, 0, 0, 255},
+	"olive": {128, 128, 0, 255}, "navy": {0, 0, 128, 255}, "purple": {128, 0, 128, 255},
+	"teal": {0, 128, 128, 255}, "orange": {255, 165, 0, 255}, "pink": {255, 192, 203, 255},
+	"brown": {165, 42, 42, 255}, "gold": {255, 215, 0, 255}, "darkgray": {169, 169, 169, 255},
+	"darkgrey": {169, 169, 169, 255}, "lightgray": {211, 211, 211, 255}, "lightgrey": {211, 211, 211, 255},
+	"darkblue": {0, 0, 139, 255}, "darkred": {139, 0, 0, 255}, "darkgreen": {0, 100, 0, 255},
+	"lightblue": {173, 216, 230, 255}, "skyblue": {135, 206, 235, 255}, "steelblue": {70, 130, 180, 255},
+	"royalblue": {65, 105, 225, 255}, "tomato": {255, 99, 71, 255}, "crimson": {220, 20, 60, 255},
+	"coral": {255, 127, 80, 255}, "salmon": {250, 128, 114, 255}, "indigo": {75, 0, 130, 255},
+	"violet": {238, 130, 238, 255}, "tan": {210, 180, 140, 255}, "beige": {245, 245, 220, 255},
+	"whitesmoke": {245, 245, 245, 255}, "gainsboro": {220, 220, 220, 255}, "dimgray": {105, 105, 105, 255},
+	"dimgrey": {105, 105, 105, 255},
+}
+
+// parseCSSColor parses a color as #rgb, #rgba, #rrggbb, #rrggbbaa, rgb(),
+// rgba(), or a common keyword; ok is false for anything else.
+func parseCSSColor(s string) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if c, ok := namedColors[s]; ok {
+		return c, true
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) == 3 || len(hex) == 4 {
+			var long strings.Builder
+			for _, ch := range hex {
+				long.WriteRune(ch)
+				long.WriteRune(ch)
+			}
+			hex = long.String()
+		}
+		if len(hex) == 6 {
+			hex += "ff"
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 8 || err != nil {
+			return color.NRGBA{}, false
+		}
+		return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+	}
+	inner, ok := strings.CutPrefix(s, "rgba(")
+	if !ok {
+		inner, ok = strings.CutPrefix(s, "rgb(")
+	}
+	if !ok || !strings.HasSuffix(inner, ")") {
+		return color.NRGBA{}, false
+	}
+	parts := strings.FieldsFunc(strings.TrimSuffix(inner, ")"), func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+	if len(parts) != 3 && len(parts) != 4 {
+		return color.NRGBA{}, false
+	}
+	var ch [4]uint8
+	ch[3] = 255
+	for i, p := range parts {
+		scale := 1.0
+		if i == 3 {
+			scale = 255
+		}
+		if num, pct := strings.CutSuffix(p, "%"); pct {
+			p, scale = num, 2.55
+		}
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return color.NRGBA{}, false
+		}
+		ch[i] = uint8(math.Max(0, math.Min(255, v*scale)) + 0.5)
+	}
+	return color.NRGBA{ch[0], ch[1], ch[2], ch[3]}, true
+}
+
+// parseOpacity parses an opacity, a number or a percentage, clamped to
+// 0..1; empty or invalid means 1.
+func parseOpacity(s string) float64 {
+	s = strings.TrimSpace(s)
+	scale := 1.0
+	if num, pct := strings.CutSuffix(s, "%"); pct {
+		s, scale = num, 0.01
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 1
+	}
+	return math.Max(0, math.Min(1, v*scale))
+}
+
+// parseLength parses an SVG length in user units. Percentages are of ref.
+// Empty or invalid lengths give def.
+func parseLength(s string, ref, def float64) float64 {
+	s = strings.TrimSpace(s)
+	units := map[string]float64{"px": 1, "pt": 4.0 / 3, "pc": 16, "mm": 96 / 25.4, "cm": 96 / 2.54, "in": 96, "em": 16, "ex": 8, "%": ref / 100}
+	scale := 1.0
+	for u, f := range units {
+		if num, ok := strings.CutSuffix(s, u); ok {
+			s, scale = num, f
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return def
+	}
+	return v * scale
+}
+
+// svgViewport returns the viewBox of the root element (zero when absent)
+// and the document's intrinsic size in CSS pixels, falling back on the
+// viewBox and then on the 300x150 default of replaced elements.
+func svgViewport(root *svgNode) (vb [4]float64, w, h float64) {
+	if v, err := parseNumbers(root.attrs["viewBox"]); err == nil && len(v) == 4 && v[2] > 0 && v[3] > 0 {
+		copy(vb[:], v)
+	}
+	abs := func(name string) float64 {
+		s := root.attrs[name]
+		if strings.HasSuffix(strings.TrimSpace(s), "%") {
+			return 0
+		}
+		return math.Max(0, parseLength(s, 0, 0))
+	}
+	w, h = abs("width"), abs("height")
+	switch {
+	case w > 0 && h > 0:
+	case vb[2] > 0 && w > 0:
+		h = w * vb[3] / vb[2]
+	case vb[2] > 0 && h > 0:
+		w = h * vb[2] / vb[3]
+	case vb[2] > 0:
+		w, h = vb[2], vb[3]
+	default:
+		w, h = 300, 150
+	}
+	return vb, w, h
+}
+
+// viewBoxTransform maps viewBox vb onto a w x h viewport as
+// preserveAspectRatio par says. A zero viewBox maps user units to pixels.
+func viewBoxTransform(vb [4]float64, w, h float64, par string) affine {
+	if vb[2] <= 0 || vb[3] <= 0 {
+		return identity
+	}
+	sx, sy := w/vb[2], h/vb[3]
+	fields := strings.Fields(par)
+	align := "xMidYMid"
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+	if align != "none" {
+		s := math.Min(sx, sy)
+		if len(fields) > 1 && fields[1] == "slice" {
+			s = math.Max(sx, sy)
+		}
+		sx, sy = s, s
+	}
+	offset := func(free float64, pos string) float64 {
+		switch pos {
+		case "Min":
+			return 0
+		case "Max":
+			return free
+		}
+		return free / 2
+	}
+	tx, ty := 0.0, 0.0
+	if align != "none" && len(align) == 8 {
+		tx = offset(w-vb[2]*sx, align[1:4])
+		ty = offset(h-vb[3]*sy, align[5:8])
+	}
+	return affine{sx, 0, 0, sy, tx - vb[0]*sx, ty - vb[1]*sy}
+}
+
+// svgRenderer draws an SVG document's elements onto dst.
+type svgRenderer struct {
+	doc *svgDoc
+	dst *image.RGBA
+	z   *vector.Rasterizer
+}
+
+// maxSVGDepth bounds element nesting and <use> chains, which may loop.
+const maxSVGDepth = 64
+
+// draw draws n and its children with the transform ctm, inheriting parent's
+// style. Group opacity is approximated by fading each descendant.
+func (rd *svgRenderer) draw(n *svgNode, ctm affine, parent map[string]string, alpha float64, depth int) error {
+	if depth > maxSVGDepth {
+		return errors.New("elements nested too deeply (a <use> loop?)")
+	}
+	st := rd.doc.style(n, parent)
+	if st["display"] == "none" {
+		return nil
+	}
+	if t := n.attrs["transform"]; t != "" {
+		m, err := parseTransform(t)
+		if err != nil {
+			return err
+		}
+		ctm = ctm.mul(m)
+	}
+	alpha *= parseOpacity(st["opacity"])
+
+	switch n.name {
+	case "svg":
+		if depth > 0 {
+			// A nested viewport.
+			vb, _, _ := svgViewport(n)
+			x, y := parseLength(n.attrs["x"], 0, 0), parseLength(n.attrs["y"], 0, 0)
+			w, h := parseLength(n.attrs["width"], 0, vb[2]), parseLength(n.attrs["height"], 0, vb[3])
+			ctm = ctm.mul(translate(x, y)).mul(viewBoxTransform(vb, w, h, n.attrs["preserveAspectRatio"]))
+		}
+		return rd.children(n, ctm, st, alpha, depth)
+	case "g", "a", "switch":
+		return rd.children(n, ctm, st, alpha, depth)
+	case "use":
+		href := n.attrs["href"]
+		id, local := strings.CutPrefix(href, "#")
+		if !local {
+			return fmt.Errorf("<use> of another file (%s) is not supported", href)
+		}
+		target, ok := rd.doc.ids[id]
+		if !ok {
+			return nil
+		}
+		ctm = ctm.mul(translate(parseLength(n.attrs["x"], 0, 0), parseLength(n.attrs["y"], 0, 0)))
+		if target.name == "symbol" {
+			vb, _, _ := svgViewport(target)
+			w, h := parseLength(n.attrs["width"], 0, vb[2]), parseLength(n.attrs["height"], 0, vb[3])
+			ctm = ctm.mul(viewBoxTransform(vb, w, h, target.attrs["preserveAspectRatio"]))
+			return rd.children(target, ctm, rd.doc.style(target, st), alpha, depth+1)
+		}
+		return rd.draw(target, ctm, st, alpha, depth+1)
+	case "image":
+		if st["visibility"] == "hidden" || st["visibility"] == "collapse" {
+			return nil
+		}
+		return rd.image(n, ctm, alpha)
+	case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+		if st["visibility"] == "hidden" || st["visibility"] == "collapse" {
+			return nil
+		}
+		s := &shape{m: ctm}
+		if err := s.addElement(n); err != nil {
+			return fmt.Errorf("<%s>: %w", n.name, err)
+		}
+		return rd.paintShape(s, st, ctm, alpha)
+	}
+	// defs, symbol, clipPath, mask, text, style, metadata and the like
+	// aren't drawn where they stand.
+	return nil
+}
+
+func (rd *svgRenderer) children(n *svgNode, ctm affine, st map[string]string, alpha float64, depth int) error {
+	for _, c := range n.children {
+		if err := rd.draw(c, ctm, st, alpha, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addElement adds the outline of a basic shape or path element.
+func (s *shape) addElement(n *svgNode) error {
+	num := func(name string) float64 { return parseLength(n.attrs[name], 0, 0) }
+	switch n.name {
+	case "path":
+		return s.addPathData(n.attrs["d"])
+	case "rect":
+		w, h := num("width"), num("height")
+		if w <= 0 || h <= 0 {
+			return nil
+		}
+		rx, ry := num("rx"), num("ry")
+		// A single radius applies to both axes.
+		if _, ok := n.attrs["rx"]; !ok {
+			rx = ry
+		}
+		if _, ok := n.attrs["ry"]; !ok {
+			ry = rx
+		}
+		s.roundRect(num("x"), num("y"), w, h, rx, ry)
+	case "circle":
+		if r := num("r"); r > 0 {
+			s.ellipse(point{num("cx"), num("cy")}, r, r)
+		}
+	case "ellipse":
+		if rx, ry := num("rx"), num("ry"); rx > 0 && ry > 0 {
+			s.ellipse(point{num("cx"), num("cy")}, rx, ry)
+		}
+	case "line":
+		s.moveTo(point{num("x1"), num("y1")})
+		s.lineTo(point{num("x2"), num("y2")})
+	case "polyline", "polygon":
+		v, err := parseNumbers(n.attrs["points"])
+		if err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(v); i += 2 {
+			if i == 0 {
+				s.moveTo(point{v[0], v[1]})
+			} else {
+				s.lineTo(point{v[i], v[i+1]})
+			}
+		}
+		if n.name == "polygon" {
+			s.close()
+		}
+	}
+	return nil
+}
+
+// paintShape fills and then strokes s as its style says.
+func (rd *svgRenderer) paintShape(s *shape, st map[string]string, ctm affine, alpha float64) error {
+	fill, ok, err := rd.doc.paint(firstOf(st["fill"], "black"), st["color"])
+	if err != nil {
+		return err
+	}
+	if ok {
+		rd.fill(s, fade(fill, alpha*parseOpacity(st["fill-opacity"])))
+	}
+	stroke, ok, err := rd.doc.paint(firstOf(st["stroke"], "none"), st["color"])
+	if err != nil {
+		return err
+	}
+	if width := parseLength(st["stroke-width"], 0, 1) * ctm.scale(); ok && width > 0 {
+		rd.stroke(s, width, fade(stroke, alpha*parseOpacity(st["stroke-opacity"])))
+	}
+	return nil
+}
+
+func fade(c color.NRGBA, f float64) color.NRGBA {
+	c.A = uint8(float64(c.A)*f + 0.5)
+	return c
+}
+
+// fill draws the inside of s, by the nonzero rule, in c.
+func (rd *svgRenderer) fill(s *shape, c color.NRGBA) {
+	rd.z.Reset(rd.dst.Bounds().Dx(), rd.dst.Bounds().Dy())
+	for _, sub := range s.subs {
+		if len(sub) < 3 {
+			continue
+		}
+		rd.z.MoveTo(float32(sub[0].x), float32(sub[0].y))
+		for _, p := range sub[1:] {
+			rd.z.LineTo(float32(p.x), float32(p.y))
+		}
+		rd.z.ClosePath()
+	}
+	rd.z.Draw(rd.dst, rd.dst.Bounds(), image.NewUniform(c), image.Point{})
+}
+
+// stroke draws the outline of s, width pixels wide, in c. Every join and
+// end is drawn round.
+func (rd *svgRenderer) stroke(s *shape, width float64, c color.NRGBA) {
+	rd.z.Reset(rd.dst.Bounds().Dx(), rd.dst.Bounds().Dy())
+	hw := width / 2
+	for i, sub := range s.subs {
+		pts := sub
+		if s.closed[i] && len(sub) > 1 {
+			pts = append(pts[:len(pts):len(pts)], sub[0])
+		}
+		for j, p := range pts {
+			rd.polygon(circle(p, hw))
+			if j == 0 {
+				continue
+			}
+			q := pts[j-1]
+			l := dist(p, q)
+			if l == 0 {
+				continue
+			}
+			nx, ny := -(p.y-q.y)/l*hw, (p.x-q.x)/l*hw
+			rd.polygon([]point{{q.x + nx, q.y + ny}, {p.x + nx, p.y + ny}, {p.x - nx, p.y - ny}, {q.x - nx, q.y - ny}})
+		}
+	}
+	rd.z.Draw(rd.dst, rd.dst.Bounds(), image.NewUniform(c), image.Point{})
+}
+
+// polygon adds pts to the rasterizer wound the same way as every other
+// stroke piece, so overlapping pieces add up instead of cancelling.
+func (rd *svgRenderer) polygon(pts []point) {
+	area := 0.0
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		area += p.x*q.y - q.x*p.y
+	}
+	if area < 0 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	rd.z.MoveTo(float32(pts[0].x), float32(pts[0].y))
+	for _, p := range pts[1:] {
+		rd.z.LineTo(float32(p.x), float32(p.y))
+	}
+	rd.z.ClosePath()
+}
+
+// circle returns a polygon approximating a circle of radius r around c.
+func circle(c point, r float64) []point {
+	n := max(8, min(64, int(math.Ceil(2*math.Pi*r/2))))
+	pts := make([]point, n)
+	for i := range pts {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+		pts[i] = point{c.x + r*cos, c.y + r*sin}
+	}
+	return pts
+}
+
+// image draws an <image> element embedded as a data: URL, sampling it at
+// each covered pixel.
+func (rd *svgRenderer) image(n *svgNode, ctm affine, alpha float64) error {
+	href := strings.TrimSpace(n.attrs["href"])
+	if !strings.HasPrefix(href, "data:") {
+		return fmt.Errorf("<image> of another file (%s) is not supported", clip(href, 60))
+	}
+	meta, payload, ok := strings.Cut(href[len("data:"):], ",")
+	if !ok {
+		return errors.New("<image> has a malformed data URL")
+	}
+	var data []byte
+	var err error
+	if strings.HasSuffix(meta, ";base64") {
+		data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+	} else {
+		var s string
+		s, err = url.PathUnescape(payload)
+		data = []byte(s)
+	}
+	if err != nil {
+		return fmt.Errorf("<image> data URL: %w", err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("<image>: %w", err)
+	}
+	ib := img.Bounds()
+	x, y := parseLength(n.attrs["x"], 0, 0), parseLength(n.attrs["y"], 0, 0)
+	w := parseLength(n.attrs["width"], 0, float64(ib.Dx()))
+	h := parseLength(n.attrs["height"], 0, float64(ib.Dy()))
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+	// Map each destination pixel back into the image (stretched to the
+	// element's box) and take the nearest source pixel.
+	toImage := affine{float64(ib.Dx()) / w, 0, 0, float64(ib.Dy()) / h, 0, 0}.mul(translate(-x, -y))
+	inv, ok := ctm.invert()
+	if !ok {
+		return nil
+	}
+	inv = toImage.mul(inv)
+	box := image.Rectangle{}
+	for _, c := range []point{{x, y}, {x + w, y}, {x, y + h}, {x + w, y + h}} {
+		p := ctm.apply(c)
+		box = box.Union(image.Rect(int(math.Floor(p.x)), int(math.Floor(p.y)), int(math.Ceil(p.x))+1, int(math.Ceil(p.y))+1))
+	}
+	box = box.Intersect(rd.dst.Bounds())
+	for py := box.Min.Y; py < box.Max.Y; py++ {
+		for px := box.Min.X; px < box.Max.X; px++ {
+			sp := inv.apply(point{float64(px) + 0.5, float64(py) + 0.5})
+			sx, sy := int(math.Floor(sp.x)), int(math.Floor(sp.y))
+			if sx < 0 || sy < 0 || sx >= ib.Dx() || sy >= ib.Dy() {
+				continue
+			}
+			r, g, b, a := img.At(ib.Min.X+sx, ib.Min.Y+sy).RGBA()
+			f := alpha
+			i := rd.dst.PixOffset(px, py)
+			d := rd.dst.Pix[i : i+4 : i+4]
+			// Source over, on premultiplied 8-bit channels.
+			keep := 1 - float64(a)/0xffff*f
+			d[0] = uint8(float64(r>>8)*f + float64(d[0])*keep + 0.5)
+			d[1] = uint8(float64(g>>8)*f + float64(d[1])*keep + 0.5)
+			d[2] = uint8(float64(b>>8)*f + float64(d[2])*keep + 0.5)
+			d[3] = uint8(float64(a>>8)*f + float64(d[3])*keep + 0.5)
+		}
+	}
+	return nil
+}
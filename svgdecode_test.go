@@ -0,0 +1,298 @@
+package main
+
+import (
+	"image"
+	"math"
+	"strings"
+	"testing"
+
+	"img2ascii/asciiart"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestParseLength(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		ref, def float64
+		want     float64
+	}{
+		{"12", 0, 0, 12},
+		{" 12.5px ", 0, 0, 12.5},
+		{"-3", 0, 0, -3},
+		{"1e2", 0, 0, 100},
+		{"1in", 0, 0, 96},
+		{"2.54cm", 0, 0, 96},
+		{"25.4mm", 0, 0, 96},
+		{"3pt", 0, 0, 4},
+		{"1pc", 0, 0, 16},
+		{"2em", 0, 0, 32},
+		{"50%", 200, 0, 100},
+		{"", 0, 7, 7},
+		{"wide", 0, 7, 7},
+		{"12qq", 0, 7, 7},
+	} {
+		if got := parseLength(tc.s, tc.ref, tc.def); !near(got, tc.want) {
+			t.Errorf("parseLength(%q, %g, %g) = %g, want %g", tc.s, tc.ref, tc.def, got, tc.want)
+		}
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want affine
+	}{
+		{"", identity},
+		{"translate(10 20)", affine{1, 0, 0, 1, 10, 20}},
+		{"translate(10)", affine{1, 0, 0, 1, 10, 0}},
+		{"scale(2)", affine{2, 0, 0, 2, 0, 0}},
+		{"scale(2,3)", affine{2, 0, 0, 3, 0, 0}},
+		{"rotate(90)", affine{0, 1, -1, 0, 0, 0}},
+		{"rotate(90 10 10)", affine{0, 1, -1, 0, 20, 0}},
+		{"skewX(45)", affine{1, 0, 1, 1, 0, 0}},
+		{"skewY(45)", affine{1, 1, 0, 1, 0, 0}},
+		{"matrix(1 2 3 4 5 6)", affine{1, 2, 3, 4, 5, 6}},
+		// A list applies right to left: scale, then translate.
+		{"translate(10,0) scale(2)", affine{2, 0, 0, 2, 10, 0}},
+		{"translate(10,0),scale(2)", affine{2, 0, 0, 2, 10, 0}},
+	} {
+		got, err := parseTransform(tc.s)
+		if err != nil {
+			t.Errorf("parseTransform(%q): %v", tc.s, err)
+			continue
+		}
+		for i := range got {
+			if !near(got[i], tc.want[i]) {
+				t.Errorf("parseTransform(%q) = %v, want %v", tc.s, got, tc.want)
+				break
+			}
+		}
+	}
+	for _, s := range []string{"translate(1", "spin(45)", "matrix(1 2 3)", "scale(x)", "rotate()"} {
+		if _, err := parseTransform(s); err == nil {
+			t.Errorf("parseTransform(%q): no error", s)
+		}
+	}
+}
+
+func TestAddPathData(t *testing.T) {
+	pathPoints := func(d string) ([][]point, []bool, error) {
+		s := &shape{m: identity}
+		err := s.addPathData(d)
+		return s.subs, s.closed, err
+	}
+	samePoints := func(a, b [][]point) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if len(a[i]) != len(b[i]) {
+				return false
+			}
+			for j := range a[i] {
+				if !near(a[i][j].x, b[i][j].x) || !near(a[i][j].y, b[i][j].y) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// Each relative path draws the same outline as its absolute twin.
+	for _, tc := range []struct{ abs, rel string }{
+		{"M10 10 L20 10 L20 20 Z", "m10 10 l10 0 l0 10 z"},
+		{"M10 10 H30 V40 H10 Z", "m10 10 h20 v30 h-20 z"},
+		{"M0 0 C10 0 20 10 20 20 S30 40 40 40", "m0 0 c10 0 20 10 20 20 s10 20 20 20"},
+		{"M0 0 Q10 0 10 10 T20 20", "m0 0 q10 0 10 10 t10 10"},
+		{"M10 0 A10 10 0 0 1 0 10", "m10 0 a10 10 0 0 1 -10 10"},
+		// After a moveto, extra pairs are linetos.
+		{"M0 0 L5 5 L10 0", "m0 0 5 5 5-5"},
+		// A subpath after z starts from the closed one's start.
+		{"M5 5 L10 5 Z M5 5 L5 10", "m5 5 l5 0 z l0 5"},
+	} {
+		abs, absClosed, err := pathPoints(tc.abs)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.abs, err)
+		}
+		rel, relClosed, err := pathPoints(tc.rel)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.rel, err)
+		}
+		if !samePoints(abs, rel) || len(absClosed) != len(relClosed) {
+			t.Errorf("%q drew %v, want %v as %q does", tc.rel, rel, abs, tc.abs)
+		}
+	}
+
+	// A quarter arc ends exactly on its endpoint and stays on the circle.
+	subs, _, err := pathPoints("M10 0 A10 10 0 0 1 0 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arc := subs[0]
+	if end := arc[len(arc)-1]; end != (point{0, 10}) {
+		t.Errorf("arc ends at %v, want {0 10}", end)
+	}
+	for _, p := range arc {
+		if r := math.Hypot(p.x, p.y); math.Abs(r-10) > 0.01 {
+			t.Errorf("arc point %v is %g from the center, want 10", p, r)
+		}
+	}
+	// The large-arc flag takes the long way around: three quarters of a
+	// turn, through the far side of the circle.
+	subs, _, err = pathPoints("M10 0 A10 10 0 1 0 0 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	minX := 0.0
+	for _, p := range subs[0] {
+		minX = math.Min(minX, p.x)
+	}
+	if !near(math.Round(minX), -10) {
+		t.Errorf("large arc reaches x = %g, want -10", minX)
+	}
+	// An arc with a zero radius is a straight line.
+	subs, _, err = pathPoints("M0 0 A0 5 0 0 1 10 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !samePoints(subs, [][]point{{{0, 0}, {10, 0}}}) {
+		t.Errorf("zero-radius arc drew %v, want a line", subs)
+	}
+	// Flags may run into the next number.
+	if _, _, err := pathPoints("M10 0a10 10 0 01-10 10"); err != nil {
+		t.Errorf("packed arc flags: %v", err)
+	}
+
+	for _, d := range []string{"L10 10", "M0 0 X5 5", "M0 0 L5", "M0 0 A5 5 0 2 1 5 5"} {
+		if _, _, err := pathPoints(d); err == nil {
+			t.Errorf("%q: no error", d)
+		}
+	}
+}
+
+func TestSVGViewport(t *testing.T) {
+	for _, tc := range []struct {
+		attrs        map[string]string
+		vb           [4]float64
+		wantW, wantH float64
+	}{
+		{map[string]string{"width": "200", "height": "100"}, [4]float64{}, 200, 100},
+		{map[string]string{"width": "1in", "height": "2in", "viewBox": "0 0 10 20"}, [4]float64{0, 0, 10, 20}, 96, 192},
+		{map[string]string{"width": "40", "viewBox": "0 0 20 10"}, [4]float64{0, 0, 20, 10}, 40, 20},
+		{map[string]string{"height": "40", "viewBox": "0 0 20 10"}, [4]float64{0, 0, 20, 10}, 80, 40},
+		{map[string]string{"viewBox": "5,5,20,10"}, [4]float64{5, 5, 20, 10}, 20, 10},
+		{map[string]string{"width": "100%", "height": "100%", "viewBox": "0 0 20 10"}, [4]float64{0, 0, 20, 10}, 20, 10},
+		{map[string]string{"viewBox": "0 0 0 10"}, [4]float64{}, 300, 150},
+		{map[string]string{}, [4]float64{}, 300, 150},
+	} {
+		vb, w, h := svgViewport(&svgNode{name: "svg", attrs: tc.attrs})
+		if vb != tc.vb || w != tc.wantW || h != tc.wantH {
+			t.Errorf("svgViewport(%v) = %v, %g, %g; want %v, %g, %g", tc.attrs, vb, w, h, tc.vb, tc.wantW, tc.wantH)
+		}
+	}
+}
+
+func TestViewBoxTransform(t *testing.T) {
+	// A 20x10 viewBox in a 100x100 viewport.
+	vb := [4]float64{0, 0, 20, 10}
+	for _, tc := range []struct {
+		par  string
+		want affine
+	}{
+		{"", affine{5, 0, 0, 5, 0, 25}},
+		{"xMidYMid meet", affine{5, 0, 0, 5, 0, 25}},
+		{"xMinYMin meet", affine{5, 0, 0, 5, 0, 0}},
+		{"xMaxYMax meet", affine{5, 0, 0, 5, 0, 50}},
+		{"xMidYMid slice", affine{10, 0, 0, 10, -50, 0}},
+		{"xMinYMin slice", affine{10, 0, 0, 10, 0, 0}},
+		{"xMaxYMax slice", affine{10, 0, 0, 10, -100, 0}},
+		{"none", affine{5, 0, 0, 10, 0, 0}},
+	} {
+		if got := viewBoxTransform(vb, 100, 100, tc.par); got != tc.want {
+			t.Errorf("viewBoxTransform(%q) = %v, want %v", tc.par, got, tc.want)
+		}
+	}
+	// The viewBox origin maps to the viewport's.
+	if got, want := viewBoxTransform([4]float64{10, 20, 10, 10}, 20, 20, "none"), (affine{2, 0, 0, 2, -20, -40}); got != want {
+		t.Errorf("offset viewBox: got %v, want %v", got, want)
+	}
+	if got := viewBoxTransform([4]float64{}, 100, 100, ""); got != identity {
+		t.Errorf("no viewBox: got %v, want the identity", got)
+	}
+}
+
+// decodeSVGString draws the SVG document s onto a w x h image.
+func decodeSVGString(s string, w, h int) (image.Image, error) {
+	return decodeSVG(strings.NewReader(s), func(float64, float64) (int, int, error) { return w, h, nil })
+}
+
+func TestSVGUseDepth(t *testing.T) {
+	for _, doc := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg"><use id="a" href="#a"/></svg>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><g id="a"><use href="#b"/></g><g id="b"><use href="#a"/></g></svg>`,
+	} {
+		_, err := decodeSVGString(doc, 8, 8)
+		if err == nil || !strings.Contains(err.Error(), "nested too deeply") {
+			t.Errorf("%s: got %v, want a nesting error", doc, err)
+		}
+	}
+	// A finite chain of <use> is drawn.
+	ok := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+		<rect id="r" width="8" height="8"/><use id="u1" xlink:href="#r"/><use xlink:href="#u1"/></svg>`
+	if _, err := decodeSVGString(ok, 8, 8); err != nil {
+		t.Errorf("chained <use>: %v", err)
+	}
+}
+
+func TestSVGRasterSize(t *testing.T) {
+	opts := asciiart.Options{Width: 80, CharAspect: 0.5}
+	if w, h, err := svgRasterSize(200, 100, opts, 8); err != nil || w != 640 || h != 320 {
+		t.Errorf("200x100 at 80 columns: got %dx%d, %v; want 640x320", w, h, err)
+	}
+	opts = asciiart.Options{Height: 10, CharAspect: 0.5}
+	if w, h, err := svgRasterSize(200, 100, opts, 8); err != nil || w != 320 || h != 160 {
+		t.Errorf("200x100 at 10 rows: got %dx%d, %v; want 320x160", w, h, err)
+	}
+	// A sliver drawn 80 columns wide would be millions of pixels tall.
+	opts = asciiart.Options{Width: 80, CharAspect: 0.5}
+	if _, _, err := svgRasterSize(1, 1e6, opts, 8); err == nil {
+		t.Error("1x1000000 at 80 columns: no error")
+	}
+	if _, _, err := svgPixels(math.Inf(1), 10); err == nil {
+		t.Error("infinite width: no error")
+	}
+	if w, h, err := svgPixels(0.2, 2.5); err != nil || w != 1 || h != 3 {
+		t.Errorf("svgPixels(0.2, 2.5) = %d, %d, %v; want 1, 3", w, h, err)
+	}
+}
+
+func TestSVGRender(t *testing.T) {
+	const doc = `<svg xmlns="http://www.w3.org/2000/svg" width="64" height="32" viewBox="0 0 64 32">
+		<style>.dot { fill: #000 }</style>
+		<rect x="0" y="0" width="64" height="32" fill="white"/>
+		<circle class="dot" cx="16" cy="16" r="12"/>
+		<path d="M40 4 h20 v24 h-20 z" fill="none" stroke="black" stroke-width="4"/>
+	</svg>`
+	img, err := decodeSVG(strings.NewReader(doc), svgPixels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := asciiart.Render(img, asciiart.Options{Width: 16, Height: 8, Charset: "@#+. "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"         +@@@@@ ",
+		"  @@@@   @    @ ",
+		" @@@@@#  @    @ ",
+		" @@@@@@  @    @ ",
+		" @@@@@@  @    @ ",
+		" @@@@@   @    @ ",
+		"  #@@    @@@@@@ ",
+		"                ",
+	}
+	if got := strings.Join(rows, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// affine is a 2D transform {a, b, c, d, e, f} mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f), as in SVG's matrix().
+type affine [6]float64
+
+var identity = affine{1, 0, 0, 1, 0, 0}
+
+// mul returns the transform that applies n and then m.
+func (m affine) mul(n affine) affine {
+	return affine{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m affine) apply(p point) point {
+	return point{m[0]*p.x + m[2]*p.y + m[4], m[1]*p.x + m[3]*p.y + m[5]}
+}
+
+// scale returns the factor by which m scales lengths on average, used for
+// stroke widths.
+func (m affine) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// invert returns the inverse of m; ok is false when m is singular.
+func (m affine) invert() (inv affine, ok bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 {
+		return inv, false
+	}
+	a, b, c, d := m[3]/det, -m[1]/det, -m[2]/det, m[0]/det
+	return affine{a, b, c, d, -(a*m[4] + c*m[5]), -(b*m[4] + d*m[5])}, true
+}
+
+func translate(x, y float64) affine { return affine{1, 0, 0, 1, x, y} }
+
+// parseTransform parses an SVG transform list such as
+// "translate(10 20) rotate(45)".
+func parseTransform(s string) (affine, error) {
+	m := identity
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, " \t\r\n,") {
+		open := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return m, fmt.Errorf("bad transform %q", s)
+		}
+		name := strings.TrimSpace(s[:open])
+		args, err := parseNumbers(s[open+1 : end])
+		if err != nil {
+			return m, fmt.Errorf("transform %s: %w", name, err)
+		}
+		s = s[end+1:]
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+		var t affine
+		switch {
+		case name == "matrix" && len(args) == 6:
+			copy(t[:], args)
+		case name == "translate" && len(args) >= 1:
+			t = translate(args[0], arg(1, 0))
+		case name == "scale" && len(args) >= 1:
+			t = affine{args[0], 0, 0, arg(1, args[0]), 0, 0}
+		case name == "rotate" && len(args) >= 1:
+			sin, cos := math.Sincos(args[0] * math.Pi / 180)
+			cx, cy := arg(1, 0), arg(2, 0)
+			t = translate(cx, cy).mul(affine{cos, sin, -sin, cos, 0, 0}).mul(translate(-cx, -cy))
+		case name == "skewX" && len(args) == 1:
+			t = affine{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case name == "skewY" && len(args) == 1:
+			t = affine{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return m, fmt.Errorf("bad transform %s with %d arguments", name, len(args))
+		}
+		m = m.mul(t)
+	}
+	return m, nil
+}
+
+type point struct{ x, y float64 }
+
+// shape collects an outline as polylines in device space. Points are given
+// in user space and mapped through m; curves are flattened after mapping so
+// their smoothness matches the output resolution.
+type shape struct {
+	m      affine
+	subs   [][]point
+	closed []bool
+}
+
+func (s *shape) moveTo(p point) {
+	s.subs = append(s.subs, []point{s.m.apply(p)})
+	s.closed = append(s.closed, false)
+}
+
+func (s *shape) lineTo(p point) {
+	if len(s.subs) == 0 {
+		s.moveTo(p)
+		return
+	}
+	i := len(s.subs) - 1
+	s.subs[i] = append(s.subs[i], s.m.apply(p))
+}
+
+// cubicTo adds a cubic Bézier curve from the current point.
+func (s *shape) cubicTo(c1, c2, p point) {
+	if len(s.subs) == 0 {
+		s.moveTo(c1)
+	}
+	i := len(s.subs) - 1
+	sub := s.subs[i]
+	p0 := sub[len(sub)-1]
+	d1, d2, d3 := s.m.apply(c1), s.m.apply(c2), s.m.apply(p)
+	// About one segment per two pixels of control polygon.
+	n := int(math.Ceil((dist(p0, d1) + dist(d1, d2) + dist(d2, d3)) / 2))
+	n = max(1, min(n, 100))
+	for k := 1; k <= n; k++ {
+		t := float64(k) / float64(n)
+		u := 1 - t
+		a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		sub = append(sub, point{
+			a*p0.x + b*d1.x + c*d2.x + d*d3.x,
+			a*p0.y + b*d1.y + c*d2.y + d*d3.y,
+		})
+	}
+	s.subs[i] = sub
+}
+
+// quadTo adds a quadratic Bézier curve from cur, the current point in user
+// space, as the equivalent cubic.
+func (s *shape) quadTo(cur, c, p point) {
+	s.cubicTo(
+		point{cur.x + 2*(c.x-cur.x)/3, cur.y + 2*(c.y-cur.y)/3},
+		point{p.x + 2*(c.x-p.x)/3, p.y + 2*(c.y-p.y)/3},
+		p,
+	)
+}
+
+// arcTo adds an elliptical arc from cur to p with SVG's endpoint
+// parameters, converted to cubic curves of at most a quarter turn each.
+func (s *shape) arcTo(cur point, rx, ry, rotation float64, large, sweep bool, p point) {
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || cur == p {
+		s.lineTo(p)
+		return
+	}
+	// Endpoint to center parameterization (SVG 1.1 appendix F.6.5).
+	sin, cos := math.Sincos(rotation * math.Pi / 180)
+	dx, dy := (cur.x-p.x)/2, (cur.y-p.y)/2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	k := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		k = -k
+	}
+	cx1, cy1 := k*rx*y1/ry, -k*ry*x1/rx
+	cx := cos*cx1 - sin*cy1 + (cur.x+p.x)/2
+	cy := sin*cx1 + cos*cy1 + (cur.y+p.y)/2
+	theta := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	delta := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx) - theta
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	at := func(a float64) (point, point) {
+		sa, ca := math.Sincos(a)
+		pos := point{cx + rx*ca*cos - ry*sa*sin, cy + rx*ca*sin + ry*sa*cos}
+		tan := point{-rx*sa*cos - ry*ca*sin, -rx*sa*sin + ry*ca*cos}
+		return pos, tan
+	}
+	n := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(n)
+	alpha := 4.0 / 3 * math.Tan(step/4)
+	for i := 0; i < n; i++ {
+		a0, a1 := theta+float64(i)*step, theta+float64(i+1)*step
+		p0, t0 := at(a0)
+		p1, t1 := at(a1)
+		if i == n-1 {
+			p1 = p
+		}
+		s.cubicTo(
+			point{p0.x + alpha*t0.x, p0.y + alpha*t0.y},
+			point{p1.x - alpha*t1.x, p1.y - alpha*t1.y},
+			p1,
+		)
+	}
+}
+
+func (s *shape) close() {
+	if len(s.closed) > 0 {
+		s.closed[len(s.closed)-1] = true
+	}
+}
+
+// ellipse adds a closed ellipse centered on c.
+func (s *shape) ellipse(c point, rx, ry float64) {
+	left, right := point{c.x - rx, c.y}, point{c.x + rx, c.y}
+	s.moveTo(right)
+	s.arcTo(right, rx, ry, 0, false, true, left)
+	s.arcTo(left, rx, ry, 0, false, true, right)
+	s.close()
+}
+
+// roundRect adds a closed rectangle whose corners are rounded with radii
+// rx and ry, which may be zero.
+func (s *shape) roundRect(x, y, w, h, rx, ry float64) {
+	rx, ry = math.Min(rx, w/2), math.Min(ry, h/2)
+	if rx <= 0 || ry <= 0 {
+		s.moveTo(point{x, y})
+		s.lineTo(point{x + w, y})
+		s.lineTo(point{x + w, y + h})
+		s.lineTo(point{x, y + h})
+		s.close()
+		return
+	}
+	corner := func(from, to point) { s.arcTo(from, rx, ry, 0, false, true, to) }
+	s.moveTo(point{x + rx, y})
+	s.lineTo(point{x + w - rx, y})
+	corner(point{x + w - rx, y}, point{x + w, y + ry})
+	s.lineTo(point{x + w, y + h - ry})
+	corner(point{x + w, y + h - ry}, point{x + w - rx, y + h})
+	s.lineTo(point{x + rx, y + h})
+	corner(point{x + rx, y + h}, point{x, y + h - ry})
+	s.lineTo(point{x, y + ry})
+	corner(point{x, y + ry}, point{x + rx, y})
+	s.close()
+}
+
+func dist(p, q point) float64 { return math.Hypot(q.x-p.x, q.y-p.y) }
+
+// pathScanner reads the numbers and commands of SVG path data.
+type pathScanner struct {
+	s string
+	i int
+}
+
+func (sc *pathScanner) skipSeparators() {
+	for sc.i < len(sc.s) && strings.IndexByte(" \t\r\n,", sc.s[sc.i]) >= 0 {
+		sc.i++
+	}
+}
+
+// hasNumber reports whether a number comes next.
+func (sc *pathScanner) hasNumber() bool {
+	sc.skipSeparators()
+	return sc.i < len(sc.s) && strings.IndexByte("+-.0123456789", sc.s[sc.i]) >= 0
+}
+
+// number reads one number. Numbers may run together, as in "1.5.5" or
+// "3-4".
+func (sc *pathScanner) number() (float64, error) {
+	sc.skipSeparators()
+	start := sc.i
+	if sc.i < len(sc.s) && (sc.s[sc.i] == '+' || sc.s[sc.i] == '-') {
+		sc.i++
+	}
+	digits := func() {
+		for sc.i < len(sc.s) && sc.s[sc.i] >= '0' && sc.s[sc.i] <= '9' {
+			sc.i++
+		}
+	}
+	digits()
+	if sc.i < len(sc.s) && sc.s[sc.i] == '.' {
+		sc.i++
+		digits()
+	}
+	if sc.i < len(sc.s) && (sc.s[sc.i] == 'e' || sc.s[sc.i] == 'E') {
+		j := sc.i + 1
+		if j < len(sc.s) && (sc.s[j] == '+' || sc.s[j] == '-') {
+			j++
+		}
+		if j < len(sc.s) && sc.s[j] >= '0' && sc.s[j] <= '9' {
+			sc.i = j
+			digits()
+		}
+	}
+	v, err := strconv.ParseFloat(sc.s[start:sc.i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad number at %q", clip(sc.s[start:], 10))
+	}
+	return v, nil
+}
+
+// flag reads an arc flag, which need not be separated from what follows.
+func (sc *pathScanner) flag() (bool, error) {
+	sc.skipSeparators()
+	if sc.i < len(sc.s) && (sc.s[sc.i] == '0' || sc.s[sc.i] == '1') {
+		sc.i++
+		return sc.s[sc.i-1] == '1', nil
+	}
+	return false, fmt.Errorf("bad arc flag at %q", clip(sc.s[sc.i:], 10))
+}
+
+func clip(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
+
+// parseNumbers parses a list of numbers separated by spaces or commas.
+func parseNumbers(s string) ([]float64, error) {
+	sc := &pathScanner{s: s}
+	var out []float64
+	for sc.hasNumber() {
+		v, err := sc.number()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if sc.skipSeparators(); sc.i < len(sc.s) {
+		return nil, fmt.Errorf("bad number at %q", clip(sc.s[sc.i:], 10))
+	}
+	return out, nil
+}
+
+// addPathData adds the outline described by SVG path data d to s.
+func (s *shape) addPathData(d string) error {
+	sc := &pathScanner{s: d}
+	var cur, start, ctrl point
+	var prev byte
+	nums := func(n int) ([]float64, error) {
+		v := make([]float64, n)
+		for i := range v {
+			var err error
+			if v[i], err = sc.number(); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	}
+	for {
+		sc.skipSeparators()
+		if sc.i >= len(sc.s) {
+			return nil
+		}
+		cmd := sc.s[sc.i]
+		if !strings.ContainsRune("MmLlHhVvCcSsQqTtAaZz", rune(cmd)) {
+			return fmt.Errorf("bad path command at %q", clip(sc.s[sc.i:], 10))
+		}
+		sc.i++
+		if prev == 0 && cmd != 'M' && cmd != 'm' {
+			return errors.New("path data must start with a moveto")
+		}
+		rel := cmd >= 'a'
+		off := func(p point) point {
+			if rel {
+				return point{cur.x + p.x, cur.y + p.y}
+			}
+			return p
+		}
+		// Arguments may repeat the command; after a moveto they are linetos.
+		for first := true; first || sc.hasNumber(); first = false {
+			upper := cmd &^ 0x20
+			switch upper {
+			case 'M':
+				v, err := nums(2)
+				if err != nil {
+					return err
+				}
+				p := off(point{v[0], v[1]})
+				if first {
+					s.moveTo(p)
+					start = p
+				} else {
+					s.lineTo(p)
+				}
+				cur = p
+			case 'L':
+				v, err := nums(2)
+				if err != nil {
+					return err
+				}
+				cur = off(point{v[0], v[1]})
+				s.lineTo(cur)
+			case 'H':
+				v, err := nums(1)
+				if err != nil {
+					return err
+				}
+				if rel {
+					cur.x += v[0]
+				} else {
+					cur.x = v[0]
+				}
+				s.lineTo(cur)
+			case 'V':
+				v, err := nums(1)
+				if err != nil {
+					return err
+				}
+				if rel {
+					cur.y += v[0]
+				} else {
+					cur.y = v[0]
+				}
+				s.lineTo(cur)
+			case 'C':
+				v, err := nums(6)
+				if err != nil {
+					return err
+				}
+				c1, c2, end := off(point{v[0], v[1]}), off(point{v[2], v[3]}), off(point{v[4], v[5]})
+				s.cubicTo(c1, c2, end)
+				cur, ctrl = end, c2
+			case 'S':
+				v, err := nums(4)
+				if err != nil {
+					return err
+				}
+				// The first control point mirrors the previous curve's last.
+				c1 := cur
+				if p := prev &^ 0x20; p == 'C' || p == 'S' {
+					c1 = point{2*cur.x - ctrl.x, 2*cur.y - ctrl.y}
+				}
+				c2, end := off(point{v[0], v[1]}), off(point{v[2], v[3]})
+				s.cubicTo(c1, c2, end)
+				cur, ctrl = end, c2
+			case 'Q':
+				v, err := nums(4)
+				if err != nil {
+					return err
+				}
+				c, end := off(point{v[0], v[1]}), off(point{v[2], v[3]})
+				s.quadTo(cur, c, end)
+				cur, ctrl = end, c
+			case 'T':
+				v, err := nums(2)
+				if err != nil {
+					return err
+				}
+				c := cur
+				if p := prev &^ 0x20; p == 'Q' || p == 'T' {
+					c = point{2*cur.x - ctrl.x, 2*cur.y - ctrl.y}
+				}
+				end := off(point{v[0], v[1]})
+				s.quadTo(cur, c, end)
+				cur, ctrl = end, c
+			case 'A':
+				v, err := nums(3)
+				if err != nil {
+					return err
+				}
+				large, err := sc.flag()
+				if err != nil {
+					return err
+				}
+				sweep, err := sc.flag()
+				if err != nil {
+					return err
+				}
+				e, err := nums(2)
+				if err != nil {
+					return err
+				}
+				end := off(point{e[0], e[1]})
+				s.arcTo(cur, v[0], v[1], v[2], large, sweep, end)
+				cur = end
+			case 'Z':
+				s.close()
+				cur = start
+				// A new subpath starts at the closed one's start point.
+				if sc.skipSeparators(); sc.i < len(sc.s) && sc.s[sc.i] != 'M' && sc.s[sc.i] != 'm' {
+					s.moveTo(cur)
+				}
+			}
+			prev = cmd
+			if upper == 'Z' {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// oscBackgroundQuery asks the terminal to report its background color.
+const oscBackgroundQuery = "\x1b]11;?\x1b\\"
+
+// parseOSCColor extracts the color from a terminal's reply to
+// oscBackgroundQuery, such as "\x1b]11;rgb:ffff/ffff/ffff\x1b\\". Each channel
+// has 1 to 4 hex digits.
+func parseOSCColor(reply string) (color.RGBA, bool) {
+	const prefix = "]11;rgb:"
+	i := strings.Index(reply, prefix)
+	if i < 0 {
+		return color.RGBA{}, false
+	}
+	s := reply[i+len(prefix):]
+	if j := strings.IndexAny(s, "\a\x1b"); j >= 0 {
+		s = s[:j]
+	}
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 {
+		return color.RGBA{}, false
+	}
+	var ch [3]uint8
+	for k, p := range parts {
+		if len(p) < 1 || len(p) > 4 {
+			return color.RGBA{}, false
+		}
+		v, err := strconv.ParseUint(p, 16, 16)
+		if err != nil {
+			return color.RGBA{}, false
+		}
+		top := uint64(1)<<(4*len(p)) - 1
+		ch[k] = uint8((v*255 + top/2) / top)
+	}
+	return color.RGBA{ch[0], ch[1], ch[2], 0xff}, true
+}
@@ -0,0 +1,34 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package main
+
+import (
+	"errors"
+	"image/color"
+	"os"
+)
+
+// stopSignals end an interactive mode. Only Ctrl-C is portable.
+var stopSignals = []os.Signal{os.Interrupt}
+
+// isTerminal reports whether f refers to a character device, the closest
+// check available on this platform.
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalSize is unsupported on this platform.
+func terminalSize(f *os.File) (cols, rows int, ok bool) {
+	return 0, 0, false
+}
+
+// keyMode is unsupported on this platform.
+func keyMode(f *os.File) (restore func(), err error) {
+	return nil, errors.New("single-key input is not supported on this platform")
+}
+
+// terminalBackground is unsupported on this platform.
+func terminalBackground() (bg color.RGBA, ok bool) {
+	return bg, false
+}
@@ -0,0 +1,104 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// stopSignals end an interactive mode: Ctrl-C, and the SIGTERM sent by kill
+// and service managers.
+var stopSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// isTerminal reports whether f refers to a terminal. Other character devices,
+// such as /dev/null, don't count.
+func isTerminal(f *os.File) bool {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&t)))
+	return errno == 0
+}
+
+// terminalSize returns the size of the terminal attached to f in character
+// cells. ok is false when f is not a terminal or the size can't be queried.
+func terminalSize(f *os.File) (cols, rows int, ok bool) {
+	var ws struct {
+		Row, Col, Xpixel, Ypixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.Col == 0 {
+		return 0, 0, false
+	}
+	return int(ws.Col), int(ws.Row), true
+}
+
+// keyMode switches the terminal attached to f to deliver each keypress
+// immediately and without echo, leaving Ctrl-C handling to the terminal.
+// restore puts back the previous settings.
+func keyMode(f *os.File) (restore func(), err error) {
+	var old syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&old))); errno != 0 {
+		return nil, errno
+	}
+	raw := old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return nil, errno
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&old)))
+	}, nil
+}
+
+// bgQueryTimeout is how long terminalBackground waits for each part of the
+// reply, in tenths of a second.
+const bgQueryTimeout = 2
+
+// terminalBackground asks the controlling terminal for its background color.
+// ok is false when there is no terminal or it doesn't answer in time.
+func terminalBackground() (bg color.RGBA, ok bool) {
+	// A raw descriptor rather than an *os.File, so reads block in the kernel
+	// and honor VTIME instead of waiting in the runtime poller.
+	fd, err := syscall.Open("/dev/tty", syscall.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return bg, false
+	}
+	defer syscall.Close(fd)
+	var old syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlGetTermios, uintptr(unsafe.Pointer(&old))); errno != 0 {
+		return bg, false
+	}
+	// The reply must neither be echoed nor wait for Enter, and a terminal
+	// that never answers must not hang the read.
+	raw := old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = bgQueryTimeout
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return bg, false
+	}
+	defer syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(&old)))
+
+	if _, err := syscall.Write(fd, []byte(oscBackgroundQuery)); err != nil {
+		return bg, false
+	}
+	var reply []byte
+	buf := make([]byte, 64)
+	for len(reply) < 256 {
+		n, err := syscall.Read(fd, buf)
+		if n <= 0 || err != nil {
+			break
+		}
+		reply = append(reply, buf[:n]...)
+		// The reply ends with BEL or ST, matching the query's terminator.
+		if bytes.HasSuffix(reply, []byte("\a")) || bytes.HasSuffix(reply, []byte("\x1b\\")) {
+			break
+		}
+	}
+	return parseOSCColor(string(reply))
+}
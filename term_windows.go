@@ -0,0 +1,78 @@
+//go:build windows
+
+package main
+
+import (
+	"image/color"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+)
+
+// Console input mode flags cleared by keyMode.
+const (
+	enableLineInput = 0x0002
+	enableEchoInput = 0x0004
+)
+
+type coord struct{ X, Y int16 }
+
+type smallRect struct{ Left, Top, Right, Bottom int16 }
+
+type consoleScreenBufferInfo struct {
+	Size              coord
+	CursorPosition    coord
+	Attributes        uint16
+	Window            smallRect
+	MaximumWindowSize coord
+}
+
+// stopSignals end an interactive mode: Ctrl-C, and the SIGTERM Go delivers
+// when the console window is closed.
+var stopSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// isTerminal reports whether f refers to a console. NUL doesn't count.
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
+
+// terminalSize returns the size of the console window attached to f in
+// character cells. ok is false when f is not a console.
+func terminalSize(f *os.File) (cols, rows int, ok bool) {
+	var info consoleScreenBufferInfo
+	r, _, _ := procGetConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, 0, false
+	}
+	cols = int(info.Window.Right-info.Window.Left) + 1
+	rows = int(info.Window.Bottom-info.Window.Top) + 1
+	return cols, rows, cols > 0
+}
+
+// keyMode switches the console attached to f to deliver each keypress
+// immediately and without echo. restore puts back the previous mode.
+func keyMode(f *os.File) (restore func(), err error) {
+	var old uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(f.Fd()), &old); err != nil {
+		return nil, err
+	}
+	r, _, err := procSetConsoleMode.Call(f.Fd(), uintptr(old&^(enableLineInput|enableEchoInput)))
+	if r == 0 {
+		return nil, err
+	}
+	return func() {
+		procSetConsoleMode.Call(f.Fd(), uintptr(old))
+	}, nil
+}
+
+// terminalBackground is not queried on Windows consoles.
+func terminalBackground() (bg color.RGBA, ok bool) {
+	return bg, false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	"golang.org/x/image/tiff"
+)
+
+// tiffSubfileTypeTag is the TIFF NewSubfileType tag, whose lowest bit marks
+// a reduced-resolution copy of another page, such as a thumbnail.
+const tiffSubfileTypeTag = 0x00FE
+
+// tiffByteOrder returns the byte order of the TIFF structure t, or nil when
+// t doesn't start with a TIFF header.
+func tiffByteOrder(t []byte) binary.ByteOrder {
+	switch {
+	case len(t) < 8:
+		return nil
+	case string(t[:4]) == "II*\x00":
+		return binary.LittleEndian
+	case string(t[:4]) == "MM\x00*":
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// tiffPages returns the offset of the IFD of each page of the TIFF file
+// data, in order, leaving out reduced-resolution copies. It stops at the
+// first IFD that is cut off or that the chain has already visited, and
+// returns nil when data isn't a TIFF.
+func tiffPages(data []byte) []uint32 {
+	bo := tiffByteOrder(data)
+	if bo == nil {
+		return nil
+	}
+	var pages []uint32
+	seen := map[uint32]bool{}
+	for off := bo.Uint32(data[4:]); off != 0 && !seen[off]; {
+		seen[off] = true
+		if off < 8 || uint64(off)+2 > uint64(len(data)) {
+			break
+		}
+		n := uint64(bo.Uint16(data[off:]))
+		end := uint64(off) + 2 + 12*n
+		if end+4 > uint64(len(data)) {
+			break
+		}
+		reduced := false
+		for e := uint64(off) + 2; e < end; e += 12 {
+			if bo.Uint16(data[e:]) == tiffSubfileTypeTag {
+				reduced = bo.Uint32(data[e+8:])&1 != 0
+				break
+			}
+		}
+		if !reduced {
+			pages = append(pages, off)
+		}
+		off = bo.Uint32(data[end:])
+	}
+	return pages
+}
+
+// setTIFFPage points the header of the TIFF file data at the IFD at off, so
+// that decoders, which read only the first IFD, decode that page. IFD
+// offsets are from the start of the file, so the rest of it stays valid.
+func setTIFFPage(data []byte, off uint32) {
+	tiffByteOrder(data).PutUint32(data[4:], off)
+}
+
+// decodeTIFFPage decodes the page of the TIFF file data whose IFD is at off.
+// If maxPixels > 0, a page with more pixels fails with a *pixelLimitError
+// before any are allocated.
+func decodeTIFFPage(data []byte, off uint32, maxPixels int) (image.Image, error) {
+	setTIFFPage(data, off)
+	if maxPixels > 0 {
+		cfg, err := tiff.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Width*cfg.Height > maxPixels {
+			return nil, &pixelLimitError{width: cfg.Width, height: cfg.Height, limit: maxPixels}
+		}
+	}
+	return tiff.Decode(bytes.NewReader(data))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// rotateOrientation maps a clockwise -rotate angle to the EXIF orientation
+// that applyOrientation turns by the same amount.
+var rotateOrientation = map[int]int{0: 1, 90: 6, 180: 3, 270: 8}
+
+// flipOrientation maps a -flip axis to the EXIF orientation that mirrors it.
+var flipOrientation = map[string]int{"": 1, "h": 2, "v": 4, "hv": 3}
+
+// rotateFlip rotates img clockwise by degrees (0, 90, 180, or 270) and then
+// mirrors it along flip ("h", "v", "hv", or "" for none).
+func rotateFlip(img image.Image, degrees int, flip string) image.Image {
+	img = applyOrientation(img, rotateOrientation[degrees])
+	return applyOrientation(img, flipOrientation[flip])
+}
+
+// cropImage returns the part of img inside r, given relative to the image's
+// top-left corner. r must lie within the image.
+func cropImage(img image.Image, r image.Rectangle) (image.Image, error) {
+	b := img.Bounds()
+	r = r.Add(b.Min)
+	if !r.In(b) {
+		return nil, fmt.Errorf("region %dx%d at %d,%d exceeds the %dx%d image",
+			r.Dx(), r.Dy(), r.Min.X-b.Min.X, r.Min.Y-b.Min.Y, b.Dx(), b.Dy())
+	}
+	if si, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return si.SubImage(r), nil
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst, nil
+}
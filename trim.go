@@ -0,0 +1,106 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"img2ascii/asciiart"
+)
+
+// trimGrid is the most cells per side of the luminance grid trimRect works
+// on.
+const trimGrid = 256
+
+// trimRect returns the part of img, relative to its top-left corner, left
+// after removing uniform borders such as black bars or white matting: the
+// rows, then the columns, along each edge whose luminance stays within
+// tolerance of that edge's outermost line. It scans a grid of block
+// averages rather than single pixels, which evens out noise such as JPEG
+// ringing, so borders come off in whole blocks. An image that is uniform
+// throughout is kept whole, as is a uniform band left between two borders.
+func trimRect(img image.Image, tolerance float64) image.Rectangle {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	full := image.Rect(0, 0, w, h)
+	if w == 0 || h == 0 {
+		return full
+	}
+	s := max(1, (max(w, h)+trimGrid-1)/trimGrid) // block size in pixels
+	gw, gh := (w+s-1)/s, (h+s-1)/s
+	lum := make([]float64, gw*gh)
+	count := make([]int, gw*gh)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			i := y/s*gw + x/s
+			lum[i] += float64(asciiart.Luminance8(r, g, bl))
+			count[i]++
+		}
+	}
+	for i := range lum {
+		lum[i] /= float64(count[i])
+	}
+
+	// border returns how many of lines, counting inward from the edge, stay
+	// within tolerance of the mean of the first; cell(i, j) is cell j of
+	// line i, and each line has n cells.
+	border := func(lines, n int, cell func(i, j int) float64) int {
+		ref := 0.0
+		for j := 0; j < n; j++ {
+			ref += cell(0, j)
+		}
+		ref /= float64(n)
+		for i := 0; i < lines; i++ {
+			for j := 0; j < n; j++ {
+				if math.Abs(cell(i, j)-ref) > tolerance {
+					return i
+				}
+			}
+		}
+		return lines
+	}
+	top := border(gh, gw, func(i, j int) float64 { return lum[i*gw+j] })
+	if top == gh {
+		return full
+	}
+	bottom := border(gh-top, gw, func(i, j int) float64 { return lum[(gh-1-i)*gw+j] })
+	if bottom == gh-top {
+		bottom = 0
+	}
+	y0, y1 := top, gh-bottom
+	left := border(gw, y1-y0, func(i, j int) float64 { return lum[(y0+j)*gw+i] })
+	if left == gw {
+		return image.Rect(0, y0*s, w, min(h, y1*s))
+	}
+	right := border(gw-left, y1-y0, func(i, j int) float64 { return lum[(y0+j)*gw+gw-1-i] })
+	if right == gw-left {
+		right = 0
+	}
+	return image.Rect(left*s, y0*s, min(w, (gw-right)*s), min(h, y1*s))
+}
+
+// trimFrames trims the uniform borders off frames. Frames of the same size,
+// as in an animation, share one region, the union of what each keeps, so
+// the picture doesn't shift between them.
+func trimFrames(frames []image.Image, tolerance float64) []image.Image {
+	rects := make([]image.Rectangle, len(frames))
+	var union image.Rectangle
+	sameSize := true
+	for i, img := range frames {
+		rects[i] = trimRect(img, tolerance)
+		union = union.Union(rects[i])
+		sameSize = sameSize && img.Bounds().Size() == frames[0].Bounds().Size()
+	}
+	trimmed := make([]image.Image, len(frames))
+	for i, img := range frames {
+		r := rects[i]
+		if sameSize {
+			r = union
+		}
+		var err error
+		if trimmed[i], err = cropImage(img, r); err != nil {
+			trimmed[i] = img
+		}
+	}
+	return trimmed
+}
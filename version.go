@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// version and commit can be set when building, e.g.
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD)"
+//
+// Otherwise they are filled in from the module and VCS information the Go
+// toolchain records in the binary.
+var (
+	version = ""
+	commit  = ""
+)
+
+// buildInfo returns the version and commit of this binary. Either is
+// "unknown" when neither the linker nor the build info supplies it. A commit
+// built from a tree with uncommitted changes is marked "-dirty".
+func buildInfo() (ver, rev string) {
+	ver, rev = version, commit
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if ver == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			ver = info.Main.Version
+		}
+		if rev == "" {
+			var dirty bool
+			for _, s := range info.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					rev = s.Value
+				case "vcs.modified":
+					dirty = s.Value == "true"
+				}
+			}
+			if rev != "" && dirty {
+				rev += "-dirty"
+			}
+		}
+	}
+	if ver == "" {
+		ver = "unknown"
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	return ver, rev
+}
+
+// writeVersion prints what -version reports.
+func writeVersion(w io.Writer) {
+	ver, rev := buildInfo()
+	fmt.Fprintf(w, "img2ascii %s\ncommit: %s\ngo: %s %s/%s\n", ver, rev, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
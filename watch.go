@@ -0,0 +1,37 @@
+package main
+
+import (
+	"image"
+	"os"
+	"time"
+)
+
+// fileVersion identifies one saved state of a watched file.
+type fileVersion struct {
+	mtime time.Time
+	size  int64
+}
+
+// watchFile calls load and then show for the file at path, and again each
+// time the file is saved, checking its modification time and size every
+// interval until stop fires. The frames of the last version seen are kept,
+// so an unchanged file is never decoded twice. While the file is missing,
+// as it briefly is when an editor saves by renaming a new copy over it, the
+// last render stays up.
+func watchFile(path string, interval time.Duration, stop <-chan os.Signal, load func(string) ([]image.Image, error), show func([]image.Image, error)) {
+	var last fileVersion
+	for {
+		if st, err := os.Stat(path); err == nil {
+			if v := (fileVersion{st.ModTime(), st.Size()}); v != last {
+				last = v
+				frames, err := load(path)
+				show(frames, err)
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
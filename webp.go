@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// maxWebPCanvas bounds the canvas of an animated WebP, which is allocated
+// whole to place its first frame on. The format allows 2^24 pixels a side.
+const maxWebPCanvas = 50_000_000
+
+// webpStill handles animated WebP files, which the WebP decoder rejects. It
+// returns a still WebP holding just the first frame of data, with where that
+// frame sits on the canvas and the canvas size. still is nil when data is not
+// an animated WebP, and it should be decoded as it is. A canvas of more than
+// maxWebPCanvas pixels fails with a *pixelLimitError, and a first frame that
+// doesn't fit on the canvas is an error too.
+func webpStill(data []byte) (still []byte, at image.Point, canvas image.Rectangle, err error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, at, canvas, nil
+	}
+	animated := false
+	for rest := data[12:]; len(rest) >= 8; {
+		fourCC, size := string(rest[:4]), int(binary.LittleEndian.Uint32(rest[4:8]))
+		if size < 0 || size > len(rest)-8 {
+			return nil, at, canvas, nil
+		}
+		payload := rest[8 : 8+size]
+		switch fourCC {
+		case "VP8X":
+			if len(payload) < 10 {
+				return nil, at, canvas, nil
+			}
+			animated = payload[0]&0x02 != 0
+			canvas = image.Rect(0, 0, int(uint24(payload[4:]))+1, int(uint24(payload[7:]))+1)
+		case "ANMF":
+			if !animated || len(payload) < 16 {
+				return nil, at, canvas, nil
+			}
+			if w, h := canvas.Dx(), canvas.Dy(); uint64(w)*uint64(h) > maxWebPCanvas {
+				return nil, at, canvas, &pixelLimitError{width: w, height: h, limit: maxWebPCanvas}
+			}
+			// Offsets are stored halved; the frame's own chunks follow the
+			// 16-byte header.
+			at = image.Pt(2*int(uint24(payload[0:])), 2*int(uint24(payload[3:])))
+			w, h := uint24(payload[6:])+1, uint24(payload[9:])+1
+			if !(image.Rectangle{Min: at, Max: at.Add(image.Pt(int(w), int(h)))}).In(canvas) {
+				return nil, at, canvas, fmt.Errorf("webp: %dx%d frame at %v is outside the %dx%d canvas", w, h, at, canvas.Dx(), canvas.Dy())
+			}
+			return stillWebP(payload[16:], w, h), at, canvas, nil
+		}
+		// Chunks are padded to an even length.
+		rest = rest[min(len(rest), 8+size+size&1):]
+	}
+	return nil, at, canvas, nil
+}
+
+// stillWebP wraps the chunks of one animation frame, w x h pixels, as a
+// WebP file of its own. A separate alpha chunk needs the extended header.
+func stillWebP(frame []byte, w, h uint32) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	if bytes.HasPrefix(frame, []byte("ALPH")) {
+		body.WriteString("VP8X")
+		binary.Write(&body, binary.LittleEndian, uint32(10))
+		body.Write([]byte{0x10, 0, 0, 0}) // alpha flag, reserved
+		body.Write(putUint24(w - 1))
+		body.Write(putUint24(h - 1))
+	}
+	body.Write(frame)
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// placeFrame draws img at at on a transparent canvas, unless it already fills
+// the canvas.
+func placeFrame(img image.Image, at image.Point, canvas image.Rectangle) image.Image {
+	if at == (image.Point{}) && img.Bounds().Size() == canvas.Size() {
+		return img
+	}
+	dst := image.NewNRGBA(canvas)
+	draw.Draw(dst, img.Bounds().Sub(img.Bounds().Min).Add(at), img, img.Bounds().Min, draw.Src)
+	return dst
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+func putUint24(v uint32) []byte {
+	return []byte{byte(v), byte(v >> 8), byte(v >> 16)}
+}